@@ -0,0 +1,258 @@
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/model"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/util"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type CartHandler struct {
+	service   service.CartService
+	Log       *zap.Logger
+	validator *helper.Validator
+	config    util.Configuration
+}
+
+func NewCartHandler(service service.CartService, logger *zap.Logger, config util.Configuration) *CartHandler {
+	return &CartHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *CartHandler) GetAllCartHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	carts, err := h.service.GetAllCartService(userID)
+	if err != nil {
+		h.Log.Error("Handler: Error getting carts", zap.Error(err))
+		h.Log.Debug("Handler: Error getting carts", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", carts)
+}
+func (h *CartHandler) GetTotalCartHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var cart struct {
+		Total_carts int `json:"total_carts"`
+	}
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	carts, err := h.service.GetTotalCartService(userID)
+	if err != nil {
+		h.Log.Error("Handler: Error getting total carts", zap.Error(err))
+		h.Log.Debug("Handler: Error getting total carts", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	cart.Total_carts = carts.TotalCarts
+
+	helper.SendJSONResponse(w, http.StatusOK, "", cart)
+}
+func (h *CartHandler) AddCartHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var cart model.Checkout
+
+	if err := json.NewDecoder(r.Body).Decode(&cart); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	cart.UserID = userID
+
+	err := h.service.AddCartService(cart)
+	if errors.Is(err, repository.ErrProductNotFound) {
+		helper.SendJSONResponse(w, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+	if errors.Is(err, repository.ErrInvalidVariant) {
+		h.Log.Warn("Handler: add cart rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		h.Log.Warn("Handler: add cart rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		h.Log.Error("Handler: add cart failed", zap.Error(err))
+		h.Log.Debug("Handler: add cart failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusCreated, "Cart successfully added", nil)
+}
+
+func (h *CartHandler) MoveWishlistToCartHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid wishlist ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid wishlist ID", nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	// The body is optional and only needed for products with variants.
+	var input struct {
+		Variant map[string]string `json:"variant"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+			h.Log.Error("Handler: invalid request payload", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+			return
+		}
+	}
+
+	cart, err := h.service.MoveWishlistToCartService(id, userID, input.Variant)
+	if err != nil {
+		switch {
+		case err.Error() == "no wishlist found", errors.Is(err, repository.ErrProductNotFound):
+			helper.SendJSONResponse(w, http.StatusNotFound, err.Error(), nil)
+		case errors.Is(err, repository.ErrInvalidVariant):
+			h.Log.Warn("Handler: move wishlist to cart rejected", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		case errors.Is(err, repository.ErrInsufficientStock):
+			h.Log.Warn("Handler: move wishlist to cart rejected", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+		default:
+			h.Log.Error("Handler: move wishlist to cart failed", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		}
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusCreated, "Wishlist successfully moved to cart", cart)
+}
+
+func (h *CartHandler) DeleteCartHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid cart ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid cart ID", nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	err = h.service.DeleteCartService(id, userID)
+	if err != nil {
+		h.Log.Error("Handler: delete cart failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Cart successfully deleted", nil)
+}
+
+func (h *CartHandler) UpdateCartHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid cart ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid cart ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var cart *model.Checkout
+
+	if err := json.NewDecoder(r.Body).Decode(&cart); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	// A quantity of 0 removes the line; anything below that is rejected.
+	if cart == nil || cart.Quantity < 0 {
+		h.Log.Warn("Handler: invalid cart quantity", zap.Int("id", id))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Quantity cannot be negative", nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	cart.ID = id
+	cart.UserID = userID
+
+	cart, err = h.service.UpdateCartService(cart.UserID, cart.ID, cart.Quantity)
+	if err != nil {
+		if errors.Is(err, repository.ErrInsufficientStock) {
+			h.Log.Warn("Handler: update cart rejected", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+			return
+		}
+		if err.Error() == "cart not found" {
+			h.Log.Warn("Handler: No cart found for user", zap.Int("userID", userID), zap.Int("id", id))
+			helper.SendJSONResponse(w, http.StatusNotFound, "Cart item not found", nil)
+			return
+		}
+		h.Log.Error("Handler: updated cart failed", zap.Error(err))
+		h.Log.Debug("Handler: updated cart failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	if cart == nil {
+
+		helper.SendJSONResponse(w, http.StatusOK, "Cart item deleted", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Cart successfully updated", cart)
+}
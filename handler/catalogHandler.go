@@ -0,0 +1,212 @@
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/model"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/util"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type CatalogHandler struct {
+	service   service.CatalogService
+	Log       *zap.Logger
+	validator *helper.Validator
+	config    util.Configuration
+}
+
+func NewCatalogHandler(service service.CatalogService, logger *zap.Logger, config util.Configuration) *CatalogHandler {
+	return &CatalogHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *CatalogHandler) GetProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid product ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid product ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	product, err := h.service.GetProductService(id)
+	if err != nil {
+		h.sendCatalogError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", product)
+}
+
+func (h *CatalogHandler) CreateProductHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var product model.CatalogProduct
+	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(product); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	if err := h.service.CreateProductService(&product); err != nil {
+		h.sendCatalogError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusCreated, "Product successfully created", product)
+}
+
+func (h *CatalogHandler) UpdateProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid product ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid product ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var product model.CatalogProduct
+	if err := json.NewDecoder(r.Body).Decode(&product); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(product); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	product.ID = id
+
+	if err := h.service.UpdateProductService(&product); err != nil {
+		h.sendCatalogError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Product successfully updated", product)
+}
+
+func (h *CatalogHandler) DeleteProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid product ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid product ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteProductService(id); err != nil {
+		h.sendCatalogError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Product successfully deleted", nil)
+}
+
+func (h *CatalogHandler) CreateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var category model.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(category); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	if err := h.service.CreateCategoryService(&category); err != nil {
+		h.sendCatalogError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusCreated, "Category successfully created", category)
+}
+
+func (h *CatalogHandler) UpdateCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid category ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid category ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var category model.Category
+	if err := json.NewDecoder(r.Body).Decode(&category); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(category); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	category.ID = id
+
+	if err := h.service.UpdateCategoryService(&category); err != nil {
+		h.sendCatalogError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Category successfully updated", category)
+}
+
+func (h *CatalogHandler) DeleteCategoryHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid category ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid category ID", nil)
+		return
+	}
+
+	if err := h.service.DeleteCategoryService(id); err != nil {
+		h.sendCatalogError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Category successfully deleted", nil)
+}
+
+func (h *CatalogHandler) sendCatalogError(w http.ResponseWriter, err error) {
+	switch {
+	case strings.HasSuffix(err.Error(), "not found"):
+		helper.SendJSONResponse(w, http.StatusNotFound, err.Error(), nil)
+	case err.Error() == "category already exists",
+		errors.Is(err, repository.ErrCategoryInUse),
+		errors.Is(err, repository.ErrInsufficientStock):
+		h.Log.Warn("Handler: catalog change rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+	default:
+		h.Log.Error("Handler: catalog change failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+	}
+}
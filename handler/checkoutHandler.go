@@ -1,223 +1,322 @@
-package handler
-
-import (
-	"ecommerce/helper"
-	"ecommerce/model"
-	"ecommerce/service"
-	"ecommerce/util"
-	"encoding/json"
-	"net/http"
-	"strconv"
-
-	"github.com/go-chi/chi/v5"
-	"go.uber.org/zap"
-)
-
-type CheckoutHandler struct {
-	service   service.CheckoutService
-	Log       *zap.Logger
-	validator *helper.Validator
-	config    util.Configuration
-}
-
-func NewCheckoutHandler(service service.CheckoutService, logger *zap.Logger, config util.Configuration) *CheckoutHandler {
-	return &CheckoutHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
-}
-
-func (h *CheckoutHandler) GetAllCartHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	carts, err := h.service.GetAllCartService(userID)
-	if err != nil {
-		h.Log.Error("Handler: Error getting carts", zap.Error(err))
-		h.Log.Debug("Handler: Error getting carts", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "", carts)
-}
-func (h *CheckoutHandler) GetTotalCartHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	var cart struct {
-		Total_carts int `json:"total_carts"`
-	}
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	carts, err := h.service.GetTotalCartService(userID)
-	if err != nil {
-		h.Log.Error("Handler: Error getting total carts", zap.Error(err))
-		h.Log.Debug("Handler: Error getting total carts", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-	
-	cart.Total_carts = carts.TotalCarts
-
-	helper.SendJSONResponse(w, http.StatusOK, "", cart)
-}
-func (h *CheckoutHandler) AddCartHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	var cart model.Checkout
-
-	if err := json.NewDecoder(r.Body).Decode(&cart); err != nil {
-		h.Log.Error("Handler: invalid request payload", zap.Error(err))
-		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
-		return
-	}
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	cart.UserID = userID
-
-	err := h.service.AddCartService(cart)
-	if err != nil {
-		h.Log.Error("Handler: add cart failed", zap.Error(err))
-		h.Log.Debug("Handler: add cart failed", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusCreated, "Cart successfully added", nil)
-}
-
-func (h *CheckoutHandler) DeleteCartHandler(w http.ResponseWriter, r *http.Request) {
-
-	id, err := strconv.Atoi(chi.URLParam(r, "id"))
-	if err != nil {
-		h.Log.Error("Handler: Invalid cart ID", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid cart ID", nil)
-		return
-	}
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	err = h.service.DeleteCartService(id, userID)
-	if err != nil {
-		h.Log.Error("Handler: delete cart failed", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "Cart successfully deleted", nil)
-}
-
-func (h *CheckoutHandler) UpdateCartHandler(w http.ResponseWriter, r *http.Request) {
-	id, err := strconv.Atoi(chi.URLParam(r, "id"))
-	if err != nil {
-		h.Log.Error("Handler: Invalid cart ID", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid cart ID", nil)
-		return
-	}
-
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	var cart *model.Checkout
-
-	if err := json.NewDecoder(r.Body).Decode(&cart); err != nil {
-		h.Log.Error("Handler: invalid request payload", zap.Error(err))
-		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
-		return
-	}
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	cart.ID = id
-	cart.UserID = userID
-
-	cart, err = h.service.UpdateCartService(cart.UserID, cart.ProductID, cart.Quantity)
-	if err != nil {
-		if err.Error() == "cart not found" {
-			h.Log.Warn("Handler: No cart found for user", zap.Int("userID", cart.UserID), zap.Int("productID", cart.ProductID))
-			helper.SendJSONResponse(w, http.StatusNotFound, "Cart item not found", nil)
-			return
-		}
-		h.Log.Error("Handler: updated cart failed", zap.Error(err))
-		h.Log.Debug("Handler: updated cart failed", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	if cart == nil {
-
-		helper.SendJSONResponse(w, http.StatusOK, "Cart item deleted", nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "Cart successfully updated", cart)
-}
-
-func (h *CheckoutHandler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	var requestData struct {
-		ProductID    []int `json:"product_id"`
-		AddressIndex int   `json:"address_index"`
-	}
-
-	err := json.NewDecoder(r.Body).Decode(&requestData)
-	if err != nil {
-		h.Log.Error("Handler: invalid request payload", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid input", nil)
-		return
-	}
-	h.Log.Info("Handler: Decoded request data", zap.Int("address_index", requestData.AddressIndex))
-
-	if len(requestData.ProductID) == 0 {
-		h.Log.Warn("Handler: No items in cart")
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Cart is empty", nil)
-		return
-	}
-
-	orderResponse, err := h.service.CreateOrderService(userID, requestData.ProductID, requestData.AddressIndex)
-
-	if err != nil {
-		h.Log.Error("Handler: failed to create order", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to create order", nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusCreated, "Order successfully created", orderResponse)
-}
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/model"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/shipping"
+	"ecommerce/util"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type CheckoutHandler struct {
+	service   service.CheckoutService
+	Log       *zap.Logger
+	validator *helper.Validator
+	config    util.Configuration
+}
+
+func NewCheckoutHandler(service service.CheckoutService, logger *zap.Logger, config util.Configuration) *CheckoutHandler {
+	return &CheckoutHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *CheckoutHandler) CreateOrderHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var requestData model.CreateOrderRequest
+
+	err := json.NewDecoder(r.Body).Decode(&requestData)
+	if err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid input", nil)
+		return
+	}
+	h.Log.Info("Handler: Decoded request data", zap.Int("address_id", requestData.AddressID))
+
+	if len(requestData.ProductID) == 0 {
+		h.Log.Warn("Handler: No items in cart")
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Cart is empty", nil)
+		return
+	}
+
+	requestData.UserID = userID
+	orderResponse, notices, err := h.service.CreateOrderService(requestData)
+
+	if errors.Is(err, service.ErrCartChanged) {
+		h.Log.Warn("Handler: cart changed before order", zap.Int("userID", userID), zap.Int("changes", len(notices)))
+		helper.SendJSONResponse(w, http.StatusConflict, "Some items in your cart have changed, please review them before ordering", notices)
+		return
+	}
+	if errors.Is(err, shipping.ErrUnknownMethod) || errors.Is(err, shipping.ErrMethodNotAvailable) || errors.Is(err, repository.ErrProductNotInCart) {
+		h.Log.Warn("Handler: order rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err != nil && err.Error() == "cart not found" {
+		h.Log.Warn("Handler: No selected items in cart", zap.Int("userID", userID))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Selected products are not in the cart", nil)
+		return
+	}
+	if err != nil && err.Error() == "address not found" {
+		h.Log.Warn("Handler: No shipping address", zap.Int("userID", userID), zap.Int("address_id", requestData.AddressID))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Address not found", nil)
+		return
+	}
+	if errors.Is(err, repository.ErrCouponNotFound) {
+		helper.SendJSONResponse(w, http.StatusNotFound, "Coupon not found", nil)
+		return
+	}
+	if errors.Is(err, repository.ErrCouponInvalid) {
+		h.Log.Warn("Handler: order rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if errors.Is(err, repository.ErrInsufficientStock) {
+		h.Log.Warn("Handler: order rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+	if err != nil {
+		h.Log.Error("Handler: failed to create order", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to create order", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusCreated, "Order successfully created", orderResponse)
+}
+
+func (h *CheckoutHandler) GetShippingMethodsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	helper.SendJSONResponse(w, http.StatusOK, "", h.service.GetShippingMethodsService())
+}
+
+func (h *CheckoutHandler) GetShippingRatesHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var requestData model.CreateOrderRequest
+	if err := json.NewDecoder(r.Body).Decode(&requestData); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid input", nil)
+		return
+	}
+
+	if len(requestData.ProductID) == 0 {
+		h.Log.Warn("Handler: No items in cart")
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Cart is empty", nil)
+		return
+	}
+
+	quotes, err := h.service.GetShippingRatesService(userID, requestData.ProductID, requestData.AddressID)
+	if errors.Is(err, repository.ErrProductNotInCart) {
+		h.Log.Warn("Handler: shipping rates rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+	if err != nil && err.Error() == "address not found" {
+		h.Log.Warn("Handler: No shipping address", zap.Int("userID", userID), zap.Int("address_id", requestData.AddressID))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Address not found", nil)
+		return
+	}
+	if err != nil {
+		h.Log.Error("Handler: failed to calculate shipping rates", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to calculate shipping rates", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", quotes)
+}
+
+func (h *CheckoutHandler) GetAllOrdersHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	status := r.URL.Query().Get("status")
+	page, err := helper.ParsePagination(r)
+	if err != nil {
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	var startDate, endDate time.Time
+	if value := r.URL.Query().Get("start_date"); value != "" {
+		startDate, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			h.Log.Warn("Handler: Invalid start_date", zap.String("start_date", value))
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid start_date, expected YYYY-MM-DD", nil)
+			return
+		}
+	}
+	if value := r.URL.Query().Get("end_date"); value != "" {
+		endDate, err = time.Parse("2006-01-02", value)
+		if err != nil {
+			h.Log.Warn("Handler: Invalid end_date", zap.String("end_date", value))
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid end_date, expected YYYY-MM-DD", nil)
+			return
+		}
+		// end_date is inclusive, so filter up to the start of the next day.
+		endDate = endDate.AddDate(0, 0, 1)
+	}
+
+	orders, page, err := h.service.GetAllOrdersService(userID, status, startDate, endDate, page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid cursor", nil)
+			return
+		}
+		h.Log.Error("Handler: Error getting orders", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	if len(orders) == 0 {
+		h.Log.Warn("Handler: No orders found", zap.Int("userID", userID), zap.String("status", status))
+		helper.SendJSONResponse(w, http.StatusNotFound, "No orders found", nil)
+		return
+	}
+
+	helper.SendJSONResponsePage(w, http.StatusOK, "", orders, page, nil)
+}
+
+func (h *CheckoutHandler) GetOrderByIdHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid order ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid order ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	order, err := h.service.GetOrderByIDService(id, userID)
+	if err != nil {
+		if err.Error() == "order not found" {
+			helper.SendJSONResponse(w, http.StatusNotFound, "Order not found", nil)
+			return
+		}
+		h.Log.Error("Handler: Error getting order", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", order)
+}
+
+func (h *CheckoutHandler) UpdateOrderStatusHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid order ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid order ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var input struct {
+		Status string `json:"status"`
+		Note   string `json:"note"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: Failed to decode request body", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if input.Status == "" {
+		h.Log.Warn("Handler: Status cannot be empty")
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Status cannot be empty", nil)
+		return
+	}
+
+	err = h.service.UpdateOrderStatusService(id, userID, input.Status, input.Note)
+	if err != nil {
+		h.sendOrderStatusError(w, err)
+		return
+	}
+
+	h.Log.Info("Handler: Order status updated", zap.Int("orderID", id), zap.String("status", input.Status))
+	helper.SendJSONResponse(w, http.StatusOK, "Order status successfully updated", nil)
+}
+
+func (h *CheckoutHandler) CancelOrderHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid order ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid order ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	err = h.service.CancelOrderService(id, userID)
+	if err != nil {
+		h.sendOrderStatusError(w, err)
+		return
+	}
+
+	h.Log.Info("Handler: Order cancelled", zap.Int("orderID", id), zap.Int("userID", userID))
+	helper.SendJSONResponse(w, http.StatusOK, "Order successfully cancelled", nil)
+}
+
+func (h *CheckoutHandler) sendOrderStatusError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "order not found":
+		helper.SendJSONResponse(w, http.StatusNotFound, "Order not found", nil)
+	case errors.Is(err, service.ErrInvalidStatusTransition), errors.Is(err, repository.ErrOrderStatusChanged):
+		h.Log.Warn("Handler: order status change rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+	default:
+		h.Log.Error("Handler: failed to update order status", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to update order status", nil)
+	}
+}
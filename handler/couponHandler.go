@@ -0,0 +1,136 @@
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/model"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/util"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type CouponHandler struct {
+	service   service.CouponService
+	Log       *zap.Logger
+	validator *helper.Validator
+	config    util.Configuration
+}
+
+func NewCouponHandler(service service.CouponService, logger *zap.Logger, config util.Configuration) *CouponHandler {
+	return &CouponHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *CouponHandler) ApplyCouponHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var input struct {
+		Code      string `json:"code"`
+		ProductID []int  `json:"product_id"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if input.Code == "" {
+		h.Log.Warn("Handler: Coupon code cannot be empty")
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Coupon code cannot be empty", nil)
+		return
+	}
+
+	preview, err := h.service.ApplyCouponService(userID, input.Code, input.ProductID)
+	if err != nil {
+		h.sendCouponError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Coupon applied", preview)
+}
+
+func (h *CouponHandler) CreateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var coupon model.Coupon
+	if err := json.NewDecoder(r.Body).Decode(&coupon); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(coupon); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+	if coupon.Type == model.CouponTypeFixed && coupon.Value <= 0 {
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Fixed value must be greater than 0", nil)
+		return
+	}
+
+	if err := h.service.CreateCouponService(&coupon); err != nil {
+		h.sendCouponError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusCreated, "Coupon successfully created", coupon)
+}
+
+func (h *CouponHandler) GetAllCouponsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	coupons, err := h.service.GetAllCouponsService()
+	if err != nil {
+		h.sendCouponError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", coupons)
+}
+
+func (h *CouponHandler) DeactivateCouponHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid coupon ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid coupon ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	if err := h.service.DeactivateCouponService(id); err != nil {
+		h.sendCouponError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Coupon successfully deactivated", nil)
+}
+
+func (h *CouponHandler) sendCouponError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrCouponNotFound):
+		helper.SendJSONResponse(w, http.StatusNotFound, "Coupon not found", nil)
+	case errors.Is(err, repository.ErrCouponInvalid):
+		h.Log.Warn("Handler: coupon rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+	case err.Error() == "coupon code already exists":
+		helper.SendJSONResponse(w, http.StatusConflict, "Coupon code already exists", nil)
+	default:
+		h.Log.Error("Handler: coupon request failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to process coupon", nil)
+	}
+}
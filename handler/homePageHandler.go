@@ -1,208 +1,364 @@
-package handler
-
-import (
-	"ecommerce/helper"
-	"ecommerce/model"
-	"ecommerce/service"
-	"ecommerce/util"
-	"encoding/json"
-	"net/http"
-	"strconv"
-
-	"github.com/go-chi/chi/v5"
-	"go.uber.org/zap"
-)
-
-type HomePageHandler struct {
-	service   service.HomePageService
-	Log       *zap.Logger
-	validator *helper.Validator
-	config    util.Configuration
-}
-
-func NewHomePageHandler(service service.HomePageService, logger *zap.Logger, config util.Configuration) *HomePageHandler {
-	return &HomePageHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
-}
-
-func (h *HomePageHandler) GetAllProductsHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	name := r.URL.Query().Get("name")
-	categoryID, _ := strconv.Atoi(r.URL.Query().Get("category_id"))
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-
-	if limit == 0 {
-		limit = 5
-	}
-	if page == 0 {
-		page = 1
-	}
-
-	products, totalItems, totalPages, err := h.service.GetAllProductsService(name, categoryID, limit, page)
-	if err != nil {
-		h.Log.Error("Handler: Error getting products", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	if len(products) == 0 {
-		h.Log.Warn("Handler: No products found", zap.String("name", name), zap.Int("category_id", categoryID))
-		helper.SendJSONResponse(w, http.StatusNotFound, "No products found", nil)
-		return
-	}
-
-	helper.SendJSONResponsePagination(w, page, limit, totalItems, totalPages, http.StatusOK, "", products)
-}
-
-func (h *HomePageHandler) GetByIdProductHandler(w http.ResponseWriter, r *http.Request) {
-	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
-
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	item, err := h.service.GetByIdProductService(id)
-	if err != nil {
-		h.Log.Error("Handler: Product not found", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusNotFound, "Product not found", nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "", item)
-}
-func (h *HomePageHandler) GetAllCategoriesHandler(w http.ResponseWriter, r *http.Request) {
-
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	item, err := h.service.GetAllCategoriesService()
-	if err != nil {
-		h.Log.Error("Handler: Error getting categories", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "", item)
-}
-
-func (h *HomePageHandler) GetAllBannersHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	banners, err := h.service.GetAllBannersService()
-	if err != nil {
-		h.Log.Error("Handler: Error getting banners", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "", banners)
-}
-func (h *HomePageHandler) GetAllBestSellingProductsHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	limit, _ := strconv.Atoi(r.URL.Query().Get("limit"))
-	page, _ := strconv.Atoi(r.URL.Query().Get("page"))
-
-	if limit == 0 {
-		limit = 5
-	}
-	if page == 0 {
-		page = 1
-	}
-
-	products, totalItems, totalPages, err := h.service.GetAllBestSellingProductsService(limit, page)
-	if err != nil {
-		h.Log.Error("Handler: Error getting products best selling", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	if len(products) == 0 {
-		h.Log.Warn("Handler: No products found", zap.Int("page", page), zap.Int("limit", limit))
-		helper.SendJSONResponse(w, http.StatusNotFound, "No products found", nil)
-		return
-	}
-
-	helper.SendJSONResponsePagination(w, page, limit, totalItems, totalPages, http.StatusOK, "", products)
-}
-
-func (h *HomePageHandler) GetAllWeeklyPromotionProductsHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	products, err := h.service.GetAllWeeklyPromotionProductsService()
-	if err != nil {
-		h.Log.Error("Handler: Error getting products weekly promotion", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "", products)
-}
-
-func (h *HomePageHandler) GetAllRecommentsProductsHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	products, err := h.service.GetAllRecommentsProductsService()
-	if err != nil {
-		h.Log.Error("Handler: Error getting products recomments", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "", products)
-}
-
-func (h *HomePageHandler) AddWishlistHandler(w http.ResponseWriter, r *http.Request) {
-	var wishlist model.Wishlist
-
-	if err := json.NewDecoder(r.Body).Decode(&wishlist); err != nil {
-		h.Log.Error("Handler: invalid request payload", zap.Error(err))
-		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
-		return
-	}
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	wishlist.UserID = userID
-
-	err := h.service.AddWishlistService(wishlist)
-	if err != nil {
-		h.Log.Error("Handler: add wishlist failed", zap.Error(err))
-		h.Log.Debug("Handler: add wishlist failed", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusCreated, "Wishlist successfully added", nil)
-
-}
-
-func (h *HomePageHandler) DeleteWishlistHandler(w http.ResponseWriter, r *http.Request) {
-
-	id, err := strconv.Atoi(chi.URLParam(r, "id"))
-	if err != nil {
-		h.Log.Error("Handler: Invalid wishlist ID", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid wishlist ID", nil)
-		return
-	}
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	err = h.service.DeleteWishlistService(id, userID)
-	if err != nil {
-		h.Log.Error("Handler: delete wishlist failed", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "Wishlist successfully deleted", nil)
-}
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/model"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/util"
+	"encoding/json"
+	"errors"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type HomePageHandler struct {
+	service   service.HomePageService
+	Log       *zap.Logger
+	validator *helper.Validator
+	config    util.Configuration
+}
+
+func NewHomePageHandler(service service.HomePageService, logger *zap.Logger, config util.Configuration) *HomePageHandler {
+	return &HomePageHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *HomePageHandler) GetAllProductsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	query := r.URL.Query()
+	filter := model.ProductFilter{Name: query.Get("name"), Sort: query.Get("sort")}
+	filter.CategoryID, _ = strconv.Atoi(query.Get("category_id"))
+	numbers := []struct {
+		name  string
+		value *float64
+	}{
+		{"min_price", &filter.MinPrice},
+		{"max_price", &filter.MaxPrice},
+		{"min_rating", &filter.MinRating},
+	}
+	for _, number := range numbers {
+		raw := query.Get(number.name)
+		if raw == "" {
+			continue
+		}
+		value, err := strconv.ParseFloat(raw, 64)
+		if err != nil || value < 0 || math.IsNaN(value) || math.IsInf(value, 0) {
+			helper.SendJSONResponse(w, http.StatusBadRequest, number.name+" must be a non-negative number", nil)
+			return
+		}
+		*number.value = value
+	}
+	filter.OnPromotion = query.Get("on_promotion") == "true"
+	filter.IsNew = query.Get("is_new") == "true"
+	page, err := helper.ParsePagination(r)
+	if err != nil {
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	// Variants are given as variant=size:M, repeated for each option.
+	for _, option := range query["variant"] {
+		key, value, found := strings.Cut(option, ":")
+		if !found || key == "" || value == "" {
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Variant must be in the form name:value", nil)
+			return
+		}
+		if filter.Variant == nil {
+			filter.Variant = map[string]string{}
+		}
+		filter.Variant[key] = value
+	}
+
+	products, facets, page, err := h.service.GetAllProductsService(filter, page)
+	if err != nil {
+		if err.Error() == "invalid sort" {
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Sort must be one of price_asc, price_desc, newest, rating, best_selling", nil)
+			return
+		}
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid cursor", nil)
+			return
+		}
+		h.Log.Error("Handler: Error getting products", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	// An empty page still carries the facets, so clients can show how to
+	// widen the filters.
+	if products == nil {
+		products = []*model.Product{}
+	}
+
+	helper.SendJSONResponsePage(w, http.StatusOK, "", products, page, facets)
+}
+
+func (h *HomePageHandler) SearchProductsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	query := strings.TrimSpace(r.URL.Query().Get("q"))
+	categoryID, _ := strconv.Atoi(r.URL.Query().Get("category_id"))
+
+	if query == "" {
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Search query is required", nil)
+		return
+	}
+	page, err := helper.ParsePagination(r)
+	if err == nil && page.Cursor != "" {
+		err = errors.New("cursor paging is not supported for search")
+	}
+	if err != nil {
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	result, totalItems, totalPages, err := h.service.SearchProductsService(query, categoryID, page.Limit, page.Page)
+	if err != nil {
+		h.Log.Error("Handler: Error searching products", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	if len(result.Items) == 0 {
+		h.Log.Warn("Handler: No products found", zap.String("q", query), zap.Int("category_id", categoryID))
+		helper.SendJSONResponse(w, http.StatusNotFound, "No products found", result)
+		return
+	}
+
+	helper.SendJSONResponsePagination(w, page.Page, page.Limit, totalItems, totalPages, http.StatusOK, "", result)
+}
+
+func (h *HomePageHandler) GetByIdProductHandler(w http.ResponseWriter, r *http.Request) {
+	id, _ := strconv.Atoi(chi.URLParam(r, "id"))
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	item, err := h.service.GetByIdProductService(id)
+	if err != nil {
+		h.Log.Error("Handler: Product not found", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusNotFound, "Product not found", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", item)
+}
+func (h *HomePageHandler) GetAllCategoriesHandler(w http.ResponseWriter, r *http.Request) {
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	item, err := h.service.GetAllCategoriesService()
+	if err != nil {
+		h.Log.Error("Handler: Error getting categories", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", item)
+}
+
+func (h *HomePageHandler) GetAllBannersHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	banners, err := h.service.GetAllBannersService()
+	if err != nil {
+		h.Log.Error("Handler: Error getting banners", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", banners)
+}
+func (h *HomePageHandler) GetAllBestSellingProductsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	page, err := helper.ParsePagination(r)
+	if err != nil {
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	products, page, err := h.service.GetAllBestSellingProductsService(page)
+	if err != nil {
+		if errors.Is(err, repository.ErrInvalidCursor) {
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid cursor", nil)
+			return
+		}
+		h.Log.Error("Handler: Error getting products best selling", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	if len(products) == 0 {
+		h.Log.Warn("Handler: No products found", zap.Int("page", page.Page), zap.Int("limit", page.Limit))
+		helper.SendJSONResponse(w, http.StatusNotFound, "No products found", nil)
+		return
+	}
+
+	helper.SendJSONResponsePage(w, http.StatusOK, "", products, page, nil)
+}
+
+func (h *HomePageHandler) GetAllWeeklyPromotionProductsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	products, err := h.service.GetAllWeeklyPromotionProductsService()
+	if err != nil {
+		h.Log.Error("Handler: Error getting products weekly promotion", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", products)
+}
+
+func (h *HomePageHandler) GetAllRecommentsProductsHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	products, err := h.service.GetAllRecommentsProductsService()
+	if err != nil {
+		h.Log.Error("Handler: Error getting products recomments", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", products)
+}
+
+func (h *HomePageHandler) AddWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	var wishlist model.Wishlist
+
+	if err := json.NewDecoder(r.Body).Decode(&wishlist); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	wishlist.UserID = userID
+
+	err := h.service.AddWishlistService(wishlist)
+	if err != nil {
+		h.Log.Error("Handler: add wishlist failed", zap.Error(err))
+		h.Log.Debug("Handler: add wishlist failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusCreated, "Wishlist successfully added", nil)
+
+}
+
+func (h *HomePageHandler) DeleteWishlistHandler(w http.ResponseWriter, r *http.Request) {
+
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid wishlist ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid wishlist ID", nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	err = h.service.DeleteWishlistService(id, userID)
+	if err != nil {
+		h.Log.Error("Handler: delete wishlist failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Wishlist successfully deleted", nil)
+}
+
+func (h *HomePageHandler) GetAllWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	wishlist, err := h.service.GetAllWishlistService(userID)
+	if err != nil {
+		h.Log.Error("Handler: Error getting wishlist", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", wishlist)
+}
+
+func (h *HomePageHandler) CreateWishlistShareHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	share, err := h.service.CreateWishlistShareService(userID)
+	if err != nil {
+		h.Log.Error("Handler: create wishlist share failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to share wishlist", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Wishlist successfully shared", share)
+}
+
+func (h *HomePageHandler) DeleteWishlistShareHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	err := h.service.DeleteWishlistShareService(userID)
+	if err != nil {
+		h.Log.Error("Handler: delete wishlist share failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusNotFound, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Wishlist share successfully deleted", nil)
+}
+
+func (h *HomePageHandler) GetSharedWishlistHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	token := chi.URLParam(r, "token")
+
+	wishlist, err := h.service.GetSharedWishlistService(token)
+	if err != nil {
+		if err.Error() == "no wishlist share found" {
+			helper.SendJSONResponse(w, http.StatusNotFound, "Wishlist not found", nil)
+			return
+		}
+		h.Log.Error("Handler: Error getting shared wishlist", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", wishlist)
+}
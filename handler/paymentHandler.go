@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/payment"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/util"
+	"errors"
+	"io"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+const maxWebhookBodyBytes = 1 << 20
+
+type PaymentHandler struct {
+	service   service.PaymentService
+	Log       *zap.Logger
+	validator *helper.Validator
+	config    util.Configuration
+}
+
+func NewPaymentHandler(service service.PaymentService, logger *zap.Logger, config util.Configuration) *PaymentHandler {
+	return &PaymentHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *PaymentHandler) CreatePaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid order ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid order ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	result, err := h.service.CreatePaymentService(id, userID)
+	if err != nil {
+		h.sendPaymentError(w, err)
+		return
+	}
+
+	h.Log.Info("Handler: Payment created", zap.Int("orderID", id), zap.Int("paymentID", result.ID))
+	helper.SendJSONResponse(w, http.StatusCreated, "Payment successfully created", result)
+}
+
+func (h *PaymentHandler) GetPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid order ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid order ID", nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	result, err := h.service.GetPaymentService(id, userID)
+	if err != nil {
+		h.sendPaymentError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", result)
+}
+
+func (h *PaymentHandler) RefundPaymentHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid order ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid order ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	result, err := h.service.RefundPaymentService(id, userID)
+	if err != nil {
+		h.sendPaymentError(w, err)
+		return
+	}
+
+	h.Log.Info("Handler: Payment refunded", zap.Int("orderID", id), zap.Int("paymentID", result.ID))
+	helper.SendJSONResponse(w, http.StatusOK, "Payment successfully refunded", result)
+}
+
+func (h *PaymentHandler) PaymentWebhookHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received payment webhook", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	payload, err := io.ReadAll(http.MaxBytesReader(w, r.Body, maxWebhookBodyBytes))
+	if err != nil {
+		h.Log.Error("Handler: Failed to read webhook body", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	err = h.service.HandleWebhookService(payload, r.Header.Get("X-Payment-Signature"))
+	if err != nil {
+		if errors.Is(err, repository.ErrDuplicatePaymentEvent) {
+			helper.SendJSONResponse(w, http.StatusOK, "Event already processed", nil)
+			return
+		}
+		// Out of order deliveries are expected; acknowledge them so the
+		// provider stops retrying, without moving the payment backwards.
+		if errors.Is(err, service.ErrInvalidPaymentTransition) || errors.Is(err, repository.ErrPaymentStatusChanged) {
+			h.Log.Warn("Handler: ignored payment webhook", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusOK, "Event ignored", nil)
+			return
+		}
+		h.sendPaymentError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Event processed", nil)
+}
+
+func (h *PaymentHandler) sendPaymentError(w http.ResponseWriter, err error) {
+	switch {
+	case err.Error() == "order not found":
+		helper.SendJSONResponse(w, http.StatusNotFound, "Order not found", nil)
+	case err.Error() == "payment not found":
+		helper.SendJSONResponse(w, http.StatusNotFound, "Payment not found", nil)
+	case errors.Is(err, payment.ErrInvalidSignature):
+		h.Log.Warn("Handler: rejected payment webhook", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "Invalid signature", nil)
+	case errors.Is(err, service.ErrOrderNotPayable), errors.Is(err, service.ErrPaymentNotRefundable),
+		errors.Is(err, service.ErrInvalidStatusTransition), errors.Is(err, repository.ErrOrderStatusChanged),
+		errors.Is(err, repository.ErrPaymentStatusChanged):
+		h.Log.Warn("Handler: payment request rejected", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+	case errors.Is(err, service.ErrPaymentAmountMismatch):
+		h.Log.Error("Handler: rejected payment webhook", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusUnprocessableEntity, err.Error(), nil)
+	default:
+		h.Log.Error("Handler: payment request failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to process payment", nil)
+	}
+}
@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/util"
+	"errors"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type PricingHandler struct {
+	service   service.PricingService
+	Log       *zap.Logger
+	validator *helper.Validator
+	config    util.Configuration
+}
+
+func NewPricingHandler(service service.PricingService, logger *zap.Logger, config util.Configuration) *PricingHandler {
+	return &PricingHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *PricingHandler) GetPriceHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid product ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid product ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	query := r.URL.Query()
+	quantity := 1
+	if raw := query.Get("quantity"); raw != "" {
+		quantity, err = strconv.Atoi(raw)
+		if err != nil || quantity < 1 {
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Quantity must be a positive number", nil)
+			return
+		}
+	}
+
+	at := time.Now()
+	if raw := query.Get("at"); raw != "" {
+		at, err = time.Parse(time.RFC3339, raw)
+		if err != nil {
+			at, err = time.ParseInLocation("2006-01-02", raw, time.Local)
+		}
+		if err != nil {
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid at, use RFC3339 or YYYY-MM-DD", nil)
+			return
+		}
+	}
+
+	// Signed-in users get their per-user coupon limits applied. Guests may
+	// preview a coupon too, but without them; checkout enforces them anyway.
+	userID, _ := r.Context().Value("userID").(int)
+
+	quote, err := h.service.QuoteService(userID, id, quantity, at, query.Get("coupon"))
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrProductNotFound):
+			helper.SendJSONResponse(w, http.StatusNotFound, "Product not found", nil)
+		case errors.Is(err, repository.ErrCouponNotFound):
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Coupon not found", nil)
+		case errors.Is(err, repository.ErrCouponInvalid):
+			h.Log.Warn("Handler: coupon rejected", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		default:
+			h.Log.Error("Handler: Error getting price", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to get price", nil)
+		}
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", quote)
+}
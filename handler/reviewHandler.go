@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/model"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/util"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type ReviewHandler struct {
+	service   service.ReviewService
+	Log       *zap.Logger
+	validator *helper.Validator
+	config    util.Configuration
+}
+
+func NewReviewHandler(service service.ReviewService, logger *zap.Logger, config util.Configuration) *ReviewHandler {
+	return &ReviewHandler{service: service, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *ReviewHandler) CreateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid product ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid product ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var review model.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(review); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	review.UserID = userID
+	review.ProductID = productID
+
+	err = h.service.CreateReviewService(&review)
+	if err != nil {
+		switch {
+		case errors.Is(err, repository.ErrReviewNotAllowed):
+			helper.SendJSONResponse(w, http.StatusForbidden, err.Error(), nil)
+		case errors.Is(err, repository.ErrReviewExists):
+			helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+		default:
+			h.Log.Error("Handler: create review failed", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to create review", nil)
+		}
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusCreated, "Review successfully added", review)
+}
+
+func (h *ReviewHandler) GetReviewsByProductHandler(w http.ResponseWriter, r *http.Request) {
+	productID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid product ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid product ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	page, err := helper.ParsePagination(r)
+	if err == nil && page.Cursor != "" {
+		err = errors.New("cursor paging is not supported for reviews")
+	}
+	if err != nil {
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	reviews, totalItems, totalPages, err := h.service.GetReviewsByProductService(productID, page.Limit, page.Page)
+	if err != nil {
+		h.Log.Error("Handler: Error getting reviews", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponsePagination(w, page.Page, page.Limit, totalItems, totalPages, http.StatusOK, "", reviews)
+}
+
+func (h *ReviewHandler) UpdateReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid review ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid review ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var review model.Review
+	if err := json.NewDecoder(r.Body).Decode(&review); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(review); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	review.ID = id
+	review.UserID = userID
+
+	updated, err := h.service.UpdateReviewService(review)
+	if err != nil {
+		if err.Error() == "review not found" {
+			helper.SendJSONResponse(w, http.StatusNotFound, "Review not found", nil)
+			return
+		}
+		h.Log.Error("Handler: update review failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to update review", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Review successfully updated", updated)
+}
+
+func (h *ReviewHandler) DeleteReviewHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid review ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid review ID", nil)
+		return
+	}
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	err = h.service.DeleteReviewService(id, userID)
+	if err != nil {
+		if err.Error() == "review not found" {
+			helper.SendJSONResponse(w, http.StatusNotFound, "Review not found", nil)
+			return
+		}
+		h.Log.Error("Handler: delete review failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to delete review", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Review successfully deleted", nil)
+}
@@ -1,364 +1,683 @@
-package handler
-
-import (
-	"ecommerce/helper"
-	"ecommerce/model"
-	"ecommerce/service"
-	"ecommerce/util"
-	"encoding/json"
-	"net/http"
-	"time"
-
-	"go.uber.org/zap"
-)
-
-type AuthHandler struct {
-	authService service.AuthService
-	Log         *zap.Logger
-	validator   *helper.Validator
-	config      util.Configuration
-}
-
-func NewAuthHandler(authService service.AuthService, logger *zap.Logger, config util.Configuration) *AuthHandler {
-	return &AuthHandler{authService: authService, Log: logger, validator: helper.NewValidator(), config: config}
-}
-
-func (h *AuthHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
-	var req model.User
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.Log.Error("Handler: invalid request payload", zap.Error(err))
-		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
-		return
-	}
-
-	if err := h.validator.ValidateStruct(req); err != nil {
-		formattedError := helper.FormatValidationError(err)
-		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
-		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
-		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
-		return
-	}
-
-	err := h.authService.RegisterService(req)
-	if err != nil {
-		h.Log.Error("Handler: register failed", zap.Error(err))
-		h.Log.Debug("Handler: register failed", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "Registration successful", nil)
-
-}
-
-func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
-	var req model.User
-
-	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
-		h.Log.Error("Handler: invalid request payload", zap.Error(err))
-		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
-		return
-	}
-
-	if err := h.validator.ValidateLoginStruct(req); err != nil {
-		formattedError := helper.FormatValidationError(err)
-		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
-		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
-		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
-		return
-	}
-
-	users, err := h.authService.LoginService(req)
-	if err != nil {
-		h.Log.Error("Handler: login failed", zap.Error(err))
-		h.Log.Debug("Handler: login failed", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, err.Error(), nil)
-		return
-	}
-	token, err := helper.GenerateToken()
-	if err != nil {
-		return
-	}
-	session := &model.Session{
-		UserID:    users.ID,
-		Token:     token,
-		ExpiresAt: time.Now().Add(24 * time.Hour),
-	}
-
-	if err := h.authService.CreateSessionService(*session); err != nil {
-		return
-	}
-
-	users.Token = token
-
-	helper.SendJSONResponse(w, http.StatusOK, "login success", users)
-}
-
-func (h *AuthHandler) GetAllAddressHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	address, err := h.authService.GetAllAddressService(userID)
-	if err != nil {
-		h.Log.Error("Handler: Error getting address", zap.Error(err))
-		h.Log.Debug("Handler: Error getting address", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "", address)
-}
-func (h *AuthHandler) GetDetailUserHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	users, err := h.authService.GetDetailUserService(userID)
-	if err != nil {
-		h.Log.Error("Handler: Error getting users", zap.Error(err))
-		h.Log.Debug("Handler: Error getting users", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "", users)
-}
-
-func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
-	token := r.Header.Get("Authorization")
-	if len(token) > 7 && token[:7] == "Bearer " {
-		token = token[7:]
-	}
-
-	if token == "" {
-		h.Log.Error("Handler: token required", zap.String("error: ", token))
-		h.Log.Debug("Handler: token required", zap.String("error: ", token))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "Token required", nil)
-		return
-	}
-
-	_, err := h.authService.VerifyToken(token)
-	if err != nil {
-		h.Log.Error("Handler: token invalid or expired", zap.Error(err))
-		h.Log.Debug("Handler: token invalid or expired", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "Invalid or expired token", nil)
-		return
-	}
-
-	if err := h.authService.Logout(token); err != nil {
-		h.Log.Error("Handler: logout failed", zap.Error(err))
-		h.Log.Debug("Handler: logout failed", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, "Logout failed", nil)
-		return
-	}
-
-	helper.SendJSONResponse(w, http.StatusOK, "Logout success", nil)
-}
-
-func (h *AuthHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request to update user", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	var input struct {
-		Name        string `json:"name"`
-		Email       string `json:"email"`
-		Phone       string `json:"phone"`
-		OldPassword string `json:"old_password"`
-		NewPassword string `json:"new_password"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		h.Log.Error("Handler: Failed to decode request body", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
-		return
-	}
-	if err := h.validator.ValidateStruct(input); err != nil {
-		formattedError := helper.FormatValidationError(err)
-		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
-		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
-		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
-		return
-	}
-	updatedUser, err := h.authService.UpdateUserService(userID, input.Name, input.Email, input.OldPassword, input.NewPassword)
-	if err != nil {
-		h.Log.Error("Handler: Failed to update user", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
-		return
-	}
-
-	h.Log.Info("Handler: User updated successfully", zap.Int("userID", updatedUser.ID))
-	helper.SendJSONResponse(w, http.StatusOK, "User updated successfully", updatedUser)
-}
-func (h *AuthHandler) UpdateAddressUserHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request to update user", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	var input struct {
-		Address []string `json:"address"`
-	}
-
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		h.Log.Error("Handler: Failed to decode request body", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
-		return
-	}
-
-	if len(input.Address) == 0 || (len(input.Address) == 1 && input.Address[0] == "") {
-		h.Log.Warn("Handler: Validation failed for address field", zap.Any("address", input.Address))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Address cannot be empty", nil)
-		return
-	}
-	if err := h.validator.ValidateStruct(input); err != nil {
-		formattedError := helper.FormatValidationError(err)
-		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
-		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
-		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
-		return
-	}
-	updatedUser, err := h.authService.UpdateAddressUserService(userID, input.Address)
-	if err != nil {
-		h.Log.Error("Handler: Failed to update user", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to update user", nil)
-		return
-	}
-
-	h.Log.Info("Handler: User updated successfully", zap.Int("userID", updatedUser.ID))
-	helper.SendJSONResponse(w, http.StatusOK, "User updated successfully", updatedUser)
-}
-func (h *AuthHandler) SetDefaultAddressUserHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request to update user", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	var input struct {
-		AddressIndex int `json:"address_index"`
-	}
-
-	err := json.NewDecoder(r.Body).Decode(&input)
-	if err != nil {
-		h.Log.Error("Handler: Failed to parse request body", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid input data", nil)
-		return
-	}
-
-	if input.AddressIndex < 0 {
-		h.Log.Warn("Handler: Validation failed for address index", zap.Int("addressIndex", input.AddressIndex))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid address index", nil)
-		return
-	}
-
-	address, err := h.authService.SetDefaultAddressService(userID, input.AddressIndex)
-	if err != nil {
-		h.Log.Error("Handler: Failed to default address", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to default address", nil)
-		return
-	}
-
-	h.Log.Info("Handler: default address successfully", zap.Int("userID", address.ID))
-	helper.SendJSONResponse(w, http.StatusOK, "default address successfully", address)
-}
-func (h *AuthHandler) DeleteAddressHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request to update user", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	var input struct {
-		AddressIndex int `json:"address_index"`
-	}
-
-	err := json.NewDecoder(r.Body).Decode(&input)
-	if err != nil {
-		h.Log.Error("Handler: Failed to parse request body", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid input data", nil)
-		return
-	}
-
-	if input.AddressIndex < 0 {
-		h.Log.Warn("Handler: Validation failed for address index", zap.Int("addressIndex", input.AddressIndex))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid address index", nil)
-		return
-	}
-
-	deleteAddress, err := h.authService.DeleteAddressService(userID, input.AddressIndex)
-	if err != nil {
-		h.Log.Error("Handler: Failed to delete address", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to delete address", nil)
-		return
-	}
-
-	h.Log.Info("Handler: User deleted successfully", zap.Int("userID", deleteAddress.ID))
-	helper.SendJSONResponse(w, http.StatusOK, "User deleted address successfully", nil)
-}
-
-func (h *AuthHandler) CreateAddressHandler(w http.ResponseWriter, r *http.Request) {
-	h.Log.Info("Handler: Received request to create address", zap.String("method", r.Method), zap.String("path", r.URL.Path))
-
-	userID, ok := r.Context().Value("userID").(int)
-	if !ok {
-		h.Log.Error("Handler: userID not found in context")
-		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
-		return
-	}
-
-	var input struct {
-		NewAddress string `json:"new_address"`
-	}
-	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
-		h.Log.Error("Handler: Failed to decode request body", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
-		return
-	}
-
-	if input.NewAddress == "" {
-		h.Log.Warn("Handler: Address cannot be empty", zap.Any("input", input))
-		helper.SendJSONResponse(w, http.StatusBadRequest, "Address cannot be empty", nil)
-		return
-	}
-
-	updatedUser, err := h.authService.CreateAddressService(userID, input.NewAddress)
-	if err != nil {
-		h.Log.Error("Handler: Failed to create address", zap.Error(err))
-		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to create address", nil)
-		return
-	}
-
-	h.Log.Info("Handler: Address created successfully", zap.Int("userID", updatedUser.ID))
-	helper.SendJSONResponse(w, http.StatusOK, "Address created successfully", updatedUser)
-}
+package handler
+
+import (
+	"ecommerce/helper"
+	"ecommerce/model"
+	"ecommerce/repository"
+	"ecommerce/service"
+	"ecommerce/util"
+	"encoding/json"
+	"errors"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+)
+
+type AuthHandler struct {
+	authService service.AuthService
+	Log         *zap.Logger
+	validator   *helper.Validator
+	config      util.Configuration
+}
+
+func NewAuthHandler(authService service.AuthService, logger *zap.Logger, config util.Configuration) *AuthHandler {
+	return &AuthHandler{authService: authService, Log: logger, validator: helper.NewValidator(), config: config}
+}
+
+func (h *AuthHandler) RegisterHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.User
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateStruct(req); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	err := h.authService.RegisterService(req)
+	if err != nil {
+		h.Log.Error("Handler: register failed", zap.Error(err))
+		h.Log.Debug("Handler: register failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Registration successful", nil)
+
+}
+
+func (h *AuthHandler) LoginHandler(w http.ResponseWriter, r *http.Request) {
+	var req model.User
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		h.Log.Debug("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	if err := h.validator.ValidateLoginStruct(req); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	users, err := h.authService.LoginService(req, clientIP(r))
+	if err != nil {
+		var lockedErr *service.LoginLockedError
+		switch {
+		case errors.As(err, &lockedErr):
+			h.Log.Warn("Handler: login throttled", zap.String("ip", clientIP(r)), zap.Duration("retryAfter", lockedErr.RetryAfter))
+			w.Header().Set("Retry-After", strconv.Itoa(int(math.Ceil(lockedErr.RetryAfter.Seconds()))))
+			helper.SendJSONResponse(w, http.StatusTooManyRequests, "Too many failed login attempts, try again later", nil)
+		case errors.Is(err, service.ErrInvalidCredentials):
+			h.Log.Warn("Handler: login failed", zap.String("ip", clientIP(r)))
+			helper.SendJSONResponse(w, http.StatusUnauthorized, "Invalid email/phone or password", nil)
+		default:
+			h.Log.Error("Handler: login failed", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusInternalServerError, "Login failed", nil)
+		}
+		return
+	}
+	session, err := h.newSession(r)
+	if err != nil {
+		h.Log.Error("Handler: failed to generate tokens", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Login failed", nil)
+		return
+	}
+	session.UserID = users.ID
+	session.Role = users.Role
+
+	if err := h.authService.CreateSessionService(session); err != nil {
+		h.Log.Error("Handler: failed to create session", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Login failed", nil)
+		return
+	}
+
+	users.Token = session.Token
+	users.RefreshToken = session.RefreshToken
+
+	helper.SendJSONResponse(w, http.StatusOK, "login success", users)
+}
+
+func (h *AuthHandler) RefreshTokenHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		RefreshToken string `json:"refresh_token"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil || input.RefreshToken == "" {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Refresh token required", nil)
+		return
+	}
+
+	session, err := h.newSession(r)
+	if err != nil {
+		h.Log.Error("Handler: failed to generate tokens", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to refresh session", nil)
+		return
+	}
+
+	err = h.authService.RefreshSessionService(input.RefreshToken, session)
+	switch {
+	case errors.Is(err, repository.ErrRefreshTokenReused):
+		h.Log.Warn("Handler: refresh token reused", zap.Int("sessionID", session.ID))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "Refresh token was already used; the session has been revoked", nil)
+		return
+	case errors.Is(err, repository.ErrRefreshTokenInvalid):
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "Invalid or expired refresh token", nil)
+		return
+	case err != nil:
+		h.Log.Error("Handler: failed to refresh session", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to refresh session", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Session refreshed", session)
+}
+
+// clientIP is the request's remote address without the port.
+func clientIP(r *http.Request) string {
+	ip, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return ip
+}
+
+// newSession issues a fresh access/refresh token pair for the client making
+// the request. The caller fills in the user.
+func (h *AuthHandler) newSession(r *http.Request) (*model.Session, error) {
+	token, err := helper.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	refreshToken, err := helper.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	return &model.Session{
+		Token:            token,
+		RefreshToken:     refreshToken,
+		UserAgent:        r.UserAgent(),
+		IPAddress:        clientIP(r),
+		ExpiresAt:        now.Add(h.config.Auth.AccessTokenTTL),
+		RefreshExpiresAt: now.Add(h.config.Auth.RefreshTokenTTL),
+	}, nil
+}
+
+func (h *AuthHandler) GetSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+	sessionID, _ := r.Context().Value("sessionID").(int)
+
+	sessions, err := h.authService.GetSessionsService(userID, sessionID)
+	if err != nil {
+		h.Log.Error("Handler: Error getting sessions", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to get sessions", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", sessions)
+}
+
+func (h *AuthHandler) RevokeSessionHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	sessionID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid session ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid session ID", nil)
+		return
+	}
+
+	if err := h.authService.RevokeSessionService(userID, sessionID); err != nil {
+		if err.Error() == "session not found" {
+			helper.SendJSONResponse(w, http.StatusNotFound, "Session not found", nil)
+			return
+		}
+		h.Log.Error("Handler: Failed to revoke session", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to revoke session", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Session revoked", zap.Int("userID", userID), zap.Int("sessionID", sessionID))
+	helper.SendJSONResponse(w, http.StatusOK, "Session revoked", nil)
+}
+
+func (h *AuthHandler) RevokeAllSessionsHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	revoked, err := h.authService.RevokeAllSessionsService(userID)
+	if err != nil {
+		h.Log.Error("Handler: Failed to revoke sessions", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to revoke sessions", nil)
+		return
+	}
+
+	h.Log.Info("Handler: All sessions revoked", zap.Int("userID", userID), zap.Int64("count", revoked))
+	helper.SendJSONResponse(w, http.StatusOK, "Logged out from all sessions", map[string]int64{"revoked": revoked})
+}
+
+func (h *AuthHandler) GetAllAddressHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	address, err := h.authService.GetAllAddressService(userID)
+	if err != nil {
+		h.Log.Error("Handler: Error getting address", zap.Error(err))
+		h.Log.Debug("Handler: Error getting address", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", address)
+}
+func (h *AuthHandler) GetDetailUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		h.Log.Debug("Handler: userID not found in context", zap.Int("userID", userID))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	users, err := h.authService.GetDetailUserService(userID)
+	if err != nil {
+		h.Log.Error("Handler: Error getting users", zap.Error(err))
+		h.Log.Debug("Handler: Error getting users", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, err.Error(), nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "", users)
+}
+
+func (h *AuthHandler) LogoutHandler(w http.ResponseWriter, r *http.Request) {
+	token := r.Header.Get("Authorization")
+	if len(token) > 7 && token[:7] == "Bearer " {
+		token = token[7:]
+	}
+
+	if token == "" {
+		h.Log.Error("Handler: token required", zap.String("error: ", token))
+		h.Log.Debug("Handler: token required", zap.String("error: ", token))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "Token required", nil)
+		return
+	}
+
+	_, err := h.authService.VerifyToken(token)
+	if err != nil {
+		h.Log.Error("Handler: token invalid or expired", zap.Error(err))
+		h.Log.Debug("Handler: token invalid or expired", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "Invalid or expired token", nil)
+		return
+	}
+
+	if err := h.authService.Logout(token); err != nil {
+		h.Log.Error("Handler: logout failed", zap.Error(err))
+		h.Log.Debug("Handler: logout failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Logout failed", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Logout success", nil)
+}
+
+func (h *AuthHandler) UpdateUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request to update user", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var input struct {
+		Name        string `json:"name"`
+		Email       string `json:"email"`
+		Phone       string `json:"phone"`
+		OldPassword string `json:"old_password"`
+		NewPassword string `json:"new_password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: Failed to decode request body", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+	if err := h.validator.ValidateStruct(input); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+	updatedUser, err := h.authService.UpdateUserService(userID, input.Name, input.Email, input.OldPassword, input.NewPassword)
+	if err != nil {
+		h.Log.Error("Handler: Failed to update user", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+		return
+	}
+
+	h.Log.Info("Handler: User updated successfully", zap.Int("userID", updatedUser.ID))
+	helper.SendJSONResponse(w, http.StatusOK, "User updated successfully", updatedUser)
+}
+func (h *AuthHandler) UpdateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request to update address", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	addressID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid address ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid address ID", nil)
+		return
+	}
+
+	var input model.Address
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: Failed to decode request body", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+	if err := h.validator.ValidateStruct(input); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+	input.ID, input.UserID = addressID, userID
+
+	address, err := h.authService.UpdateAddressService(input)
+	if err != nil {
+		h.sendAddressError(w, err)
+		return
+	}
+
+	h.Log.Info("Handler: Address updated successfully", zap.Int("userID", userID), zap.Int("addressID", address.ID))
+	helper.SendJSONResponse(w, http.StatusOK, "Address updated successfully", address)
+}
+func (h *AuthHandler) SetDefaultAddressUserHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request to update user", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var input struct {
+		AddressID int `json:"address_id"`
+	}
+
+	err := json.NewDecoder(r.Body).Decode(&input)
+	if err != nil {
+		h.Log.Error("Handler: Failed to parse request body", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid input data", nil)
+		return
+	}
+
+	if input.AddressID <= 0 {
+		h.Log.Warn("Handler: Validation failed for address id", zap.Int("addressID", input.AddressID))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid address ID", nil)
+		return
+	}
+
+	user, err := h.authService.SetDefaultAddressService(userID, input.AddressID)
+	if err != nil {
+		h.sendAddressError(w, err)
+		return
+	}
+
+	h.Log.Info("Handler: default address successfully", zap.Int("userID", user.ID))
+	helper.SendJSONResponse(w, http.StatusOK, "default address successfully", user)
+}
+func (h *AuthHandler) DeleteAddressHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request to delete address", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	addressID, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid address ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid address ID", nil)
+		return
+	}
+
+	if err := h.authService.DeleteAddressService(userID, addressID); err != nil {
+		h.sendAddressError(w, err)
+		return
+	}
+
+	h.Log.Info("Handler: Address deleted successfully", zap.Int("userID", userID), zap.Int("addressID", addressID))
+	helper.SendJSONResponse(w, http.StatusOK, "User deleted address successfully", nil)
+}
+
+func (h *AuthHandler) CreateAddressHandler(w http.ResponseWriter, r *http.Request) {
+	h.Log.Info("Handler: Received request to create address", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var input model.Address
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: Failed to decode request body", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+	if err := h.validator.ValidateStruct(input); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		h.Log.Debug("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+	input.UserID = userID
+
+	address, err := h.authService.CreateAddressService(input)
+	if err != nil {
+		h.sendAddressError(w, err)
+		return
+	}
+
+	h.Log.Info("Handler: Address created successfully", zap.Int("userID", userID), zap.Int("addressID", address.ID))
+	helper.SendJSONResponse(w, http.StatusCreated, "Address created successfully", address)
+}
+
+func (h *AuthHandler) sendAddressError(w http.ResponseWriter, err error) {
+	if err.Error() == "address not found" {
+		h.Log.Warn("Handler: address not found", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusNotFound, "Address not found", nil)
+		return
+	}
+	h.Log.Error("Handler: address change failed", zap.Error(err))
+	helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to save address", nil)
+}
+
+func (h *AuthHandler) UpdateRoleHandler(w http.ResponseWriter, r *http.Request) {
+	id, err := strconv.Atoi(chi.URLParam(r, "id"))
+	if err != nil {
+		h.Log.Error("Handler: Invalid user ID", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid user ID", nil)
+		return
+	}
+
+	h.Log.Info("Handler: Received request to update role", zap.String("method", r.Method), zap.String("path", r.URL.Path))
+
+	var input struct {
+		Role string `json:"role"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: Failed to decode request body", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	user, err := h.authService.UpdateRoleService(id, input.Role)
+	if err != nil {
+		switch err.Error() {
+		case "invalid role":
+			helper.SendJSONResponse(w, http.StatusBadRequest, "Role must be one of customer, staff, admin", nil)
+		case "user not found":
+			helper.SendJSONResponse(w, http.StatusNotFound, "User not found", nil)
+		default:
+			h.Log.Error("Handler: Failed to update role", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to update role", nil)
+		}
+		return
+	}
+
+	h.Log.Info("Handler: Role updated successfully", zap.Int("userID", user.ID), zap.String("role", user.Role))
+	helper.SendJSONResponse(w, http.StatusOK, "Role updated successfully", user)
+}
+
+func (h *AuthHandler) ForgotPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email string `json:"email"`
+		Phone string `json:"phone"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+	if input.Email == "" && input.Phone == "" {
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Email or Phone is required", nil)
+		return
+	}
+
+	if err := h.authService.RequestPasswordResetService(input.Email, input.Phone); err != nil {
+		h.Log.Error("Handler: failed to send reset code", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Failed to send reset code", nil)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "If the account exists, a reset code has been sent", nil)
+}
+
+func (h *AuthHandler) ResetPasswordHandler(w http.ResponseWriter, r *http.Request) {
+	var input struct {
+		Email            string `json:"email"`
+		Phone            string `json:"phone"`
+		VerificationCode string `json:"code" validate:"required"`
+		NewPassword      string `json:"new_password" validate:"required,min=8"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+	if input.Email == "" && input.Phone == "" {
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Email or Phone is required", nil)
+		return
+	}
+	if err := h.validator.ValidateStruct(input); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	err := h.authService.ResetPasswordService(input.Email, input.Phone, input.VerificationCode, input.NewPassword)
+	if err != nil {
+		h.sendVerificationError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Password has been reset, please log in again", nil)
+}
+
+func (h *AuthHandler) RequestVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var input struct {
+		Channel string `json:"channel"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+
+	if err := h.authService.RequestVerificationService(userID, input.Channel); err != nil {
+		h.sendVerificationError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Verification code sent", nil)
+}
+
+func (h *AuthHandler) ConfirmVerificationHandler(w http.ResponseWriter, r *http.Request) {
+	userID, ok := r.Context().Value("userID").(int)
+	if !ok {
+		h.Log.Error("Handler: userID not found in context")
+		helper.SendJSONResponse(w, http.StatusUnauthorized, "User ID not found", nil)
+		return
+	}
+
+	var input struct {
+		Channel          string `json:"channel"`
+		VerificationCode string `json:"code" validate:"required"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&input); err != nil {
+		h.Log.Error("Handler: invalid request payload", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid request body", nil)
+		return
+	}
+	if err := h.validator.ValidateStruct(input); err != nil {
+		formattedError := helper.FormatValidationError(err)
+		h.Log.Error("Handler: validation failed", zap.String("error", formattedError))
+		helper.SendJSONResponse(w, http.StatusBadRequest, formattedError, nil)
+		return
+	}
+
+	if err := h.authService.ConfirmVerificationService(userID, input.Channel, input.VerificationCode); err != nil {
+		h.sendVerificationError(w, err)
+		return
+	}
+
+	helper.SendJSONResponse(w, http.StatusOK, "Contact verified", nil)
+}
+
+func (h *AuthHandler) sendVerificationError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, repository.ErrVerificationCodeInvalid):
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Invalid or expired code", nil)
+	case errors.Is(err, repository.ErrVerificationCodeLocked):
+		h.Log.Warn("Handler: verification code locked", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusTooManyRequests, err.Error(), nil)
+	case errors.Is(err, service.ErrTooManyCodeRequests):
+		h.Log.Warn("Handler: code request throttled", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusTooManyRequests, err.Error(), nil)
+	case errors.Is(err, service.ErrAlreadyVerified):
+		helper.SendJSONResponse(w, http.StatusConflict, err.Error(), nil)
+	case err.Error() == "invalid channel":
+		helper.SendJSONResponse(w, http.StatusBadRequest, "Channel must be email or sms", nil)
+	case err.Error() == "no email on account", err.Error() == "no phone on account":
+		helper.SendJSONResponse(w, http.StatusBadRequest, err.Error(), nil)
+	default:
+		h.Log.Error("Handler: verification failed", zap.Error(err))
+		helper.SendJSONResponse(w, http.StatusInternalServerError, "Verification failed", nil)
+	}
+}
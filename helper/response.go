@@ -1,35 +1,89 @@
-package helper
-
-import (
-	"ecommerce/model"
-	"encoding/json"
-	"net/http"
-)
-
-func SendJSONResponse(w http.ResponseWriter, status int, message string, data interface{}) {
-	response := model.Response{
-		Status:  status,
-		Message: message,
-		Data:    data,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(response)
-}
-
-func SendJSONResponsePagination(w http.ResponseWriter, page, limit, totalItems, totalPages, status int, message string, data interface{}) {
-	response := model.Response{
-		Page:       page,
-		Limit:      limit,
-		TotalItems: totalItems,
-		TotalPages: totalPages,
-		Status:     status,
-		Message:    message,
-		Data:       data,
-	}
-
-	w.Header().Set("Content-Type", "application/json")
-	w.WriteHeader(status)
-	json.NewEncoder(w).Encode(response)
-}
\ No newline at end of file
+package helper
+
+import (
+	"ecommerce/model"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strconv"
+)
+
+func SendJSONResponse(w http.ResponseWriter, status int, message string, data interface{}) {
+	response := model.Response{
+		Status:  status,
+		Message: message,
+		Data:    data,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+func SendJSONResponsePagination(w http.ResponseWriter, page, limit, totalItems, totalPages, status int, message string, data interface{}) {
+	response := model.Response{
+		Page:       page,
+		Limit:      limit,
+		TotalItems: totalItems,
+		TotalPages: totalPages,
+		Status:     status,
+		Message:    message,
+		Data:       data,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// SendJSONResponsePage sends one page of a listing with its cursors and, for
+// listings that have them, facet counts.
+func SendJSONResponsePage(w http.ResponseWriter, status int, message string, data interface{}, page model.Pagination, facets interface{}) {
+	response := model.Response{
+		Page:       page.Page,
+		Limit:      page.Limit,
+		TotalItems: page.TotalItems,
+		TotalPages: page.TotalPages,
+		NextCursor: page.NextCursor,
+		PrevCursor: page.PrevCursor,
+		Status:     status,
+		Message:    message,
+		Data:       data,
+		Facets:     facets,
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(response)
+}
+
+// MaxPageLimit caps how many items a single page may ask for.
+const MaxPageLimit = 100
+
+// ParsePagination reads limit, page and cursor from the query string. A
+// cursor takes precedence over page, which is then left out of the response.
+// Missing values get defaults, a limit above MaxPageLimit is lowered to it,
+// and anything that is not a positive number is an error for a 400.
+func ParsePagination(r *http.Request) (model.Pagination, error) {
+	limit, page := 5, 1
+	if value := r.URL.Query().Get("limit"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return model.Pagination{}, errors.New("limit must be a positive number")
+		}
+		limit = min(n, MaxPageLimit)
+	}
+	if value := r.URL.Query().Get("page"); value != "" {
+		n, err := strconv.Atoi(value)
+		if err != nil || n < 1 {
+			return model.Pagination{}, errors.New("page must be a positive number")
+		}
+		page = n
+	}
+
+	cursor := r.URL.Query().Get("cursor")
+	if cursor != "" {
+		page = 0
+	}
+	return model.Pagination{Page: page, Limit: limit, Cursor: cursor}, nil
+}
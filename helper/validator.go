@@ -1,68 +1,109 @@
-package helper
-
-import (
-	"ecommerce/model"
-	"regexp"
-	"strings"
-
-	"github.com/go-playground/validator/v10"
-)
-
-type Validator struct {
-	validate *validator.Validate
-}
-
-func NewValidator() *Validator {
-	v := validator.New()
-
-	v.RegisterValidation("regex", func(fl validator.FieldLevel) bool {
-		pattern := fl.Param()
-		value := fl.Field().String()
-
-		matched, err := regexp.MatchString(pattern, value)
-		if err != nil {
-			return false
-		}
-		return matched
-	})
-
-	return &Validator{validate: v}
-}
-
-func (v *Validator) ValidateStruct(data interface{}) error {
-	return v.validate.Struct(data)
-}
-func (v *Validator) ValidateLoginStruct(req model.User) error {
-	return v.validate.StructExcept(req, "Name")
-}
-
-func FormatValidationError(err error) string {
-	errorMessages := map[string]string{
-		"Name_required":          "Name is required",
-		"Name_min":               "Name must have at least 3 characters",
-		"Name_regex":             "Name must only contain letters and spaces",
-		"Email_required_without": "Email or Phone is required",
-		"Email_email":            "Email format is invalid",
-		"Phone_required_without": "Phone or Email is required",
-		"Phone_min":              "Phone number must be at least 10 digits",
-		"Phone_max":              "Phone number must be at most 13 digits",
-		"Phone_numeric":          "Phone number must be numeric",
-		"Password_required":      "Password is required",
-		"Password_min":           "Password must have at least 8 characters",
-	}
-
-	var errMessages []string
-	for _, err := range err.(validator.ValidationErrors) {
-
-		key := err.Field() + "_" + err.Tag()
-
-		if message, found := errorMessages[key]; found {
-			errMessages = append(errMessages, message)
-		} else {
-			errMessages = append(errMessages, err.Field()+" is invalid: "+err.Tag())
-		}
-	}
-
-	return strings.Join(errMessages, ", ")
-
-}
+package helper
+
+import (
+	"ecommerce/model"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/validator/v10"
+)
+
+type Validator struct {
+	validate *validator.Validate
+}
+
+func NewValidator() *Validator {
+	v := validator.New()
+
+	v.RegisterValidation("regex", func(fl validator.FieldLevel) bool {
+		pattern := fl.Param()
+		value := fl.Field().String()
+
+		matched, err := regexp.MatchString(pattern, value)
+		if err != nil {
+			return false
+		}
+		return matched
+	})
+
+	// A percentage coupon above 100 would discount more than the goods cost.
+	v.RegisterStructValidation(func(sl validator.StructLevel) {
+		coupon := sl.Current().Interface().(model.Coupon)
+		if coupon.Type == model.CouponTypePercentage && (coupon.Value <= 0 || coupon.Value > 100) {
+			sl.ReportError(coupon.Value, "Value", "Value", "percentage", "")
+		}
+	}, model.Coupon{})
+
+	return &Validator{validate: v}
+}
+
+func (v *Validator) ValidateStruct(data interface{}) error {
+	return v.validate.Struct(data)
+}
+func (v *Validator) ValidateLoginStruct(req model.User) error {
+	return v.validate.StructExcept(req, "Name")
+}
+
+func FormatValidationError(err error) string {
+	errorMessages := map[string]string{
+		"Name_required":             "Name is required",
+		"Name_min":                  "Name must have at least 3 characters",
+		"Name_regex":                "Name must only contain letters and spaces",
+		"Email_required_without":    "Email or Phone is required",
+		"Email_email":               "Email format is invalid",
+		"Phone_required_without":    "Phone or Email is required",
+		"Phone_min":                 "Phone number must be at least 10 digits",
+		"Phone_max":                 "Phone number must be at most 13 digits",
+		"Phone_numeric":             "Phone number must be numeric",
+		"Password_required":         "Password is required",
+		"Password_min":              "Password must have at least 8 characters",
+		"Rating_required":           "Rating is required",
+		"Rating_min":                "Rating must be between 1 and 5",
+		"Rating_max":                "Rating must be between 1 and 5",
+		"Review_max":                "Review must be at most 255 characters",
+		"CategoryID_required":       "Category is required",
+		"Title_required":            "Title is required",
+		"Subtitle_required":         "Subtitle is required",
+		"Images_required":           "At least one image is required",
+		"Images_min":                "At least one image is required",
+		"Price_required":            "Price is required",
+		"Price_gt":                  "Price must be greater than 0",
+		"Stock_min":                 "Stock cannot be negative",
+		"Weight_min":                "Weight must be at least 1 gram",
+		"PriceTiers_unique":         "Price tiers must have different minimum quantities",
+		"MinQuantity_min":           "Tier minimum quantity must be at least 2",
+		"DiscountPercentage_min":    "Tier discount must be between 1 and 100",
+		"DiscountPercentage_max":    "Tier discount must be between 1 and 100",
+		"Code_required":             "Coupon code is required",
+		"Code_max":                  "Coupon code must be at most 30 characters",
+		"Type_required":             "Coupon type is required",
+		"Type_oneof":                "Coupon type must be percentage, fixed or free_shipping",
+		"Value_percentage":          "Percentage value must be between 1 and 100",
+		"EndsAt_required":           "End date is required",
+		"EndsAt_gtfield":            "End date must be after start date",
+		"Label_max":                 "Label must be at most 50 characters",
+		"Recipient_required":        "Recipient is required",
+		"Street_required":           "Street is required",
+		"City_required":             "City is required",
+		"Province_required":         "Province is required",
+		"PostalCode_numeric":        "Postal code must be numeric",
+		"VerificationCode_required": "Code is required",
+		"NewPassword_required":      "New password is required",
+		"NewPassword_min":           "New password must have at least 8 characters",
+	}
+
+	var errMessages []string
+	for _, err := range err.(validator.ValidationErrors) {
+
+		key := err.Field() + "_" + err.Tag()
+
+		if message, found := errorMessages[key]; found {
+			errMessages = append(errMessages, message)
+		} else {
+			errMessages = append(errMessages, err.Field()+" is invalid: "+err.Tag())
+		}
+	}
+
+	return strings.Join(errMessages, ", ")
+
+}
@@ -0,0 +1,165 @@
+package jwt
+
+import (
+	"crypto/ed25519"
+	"crypto/hmac"
+	"crypto/sha256"
+	"ecommerce/util"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidToken = errors.New("invalid token")
+	ErrExpiredToken = errors.New("token has expired")
+)
+
+const (
+	AlgHS256 = "HS256"
+	AlgEdDSA = "EdDSA"
+
+	// minHS256SecretBytes matches the size of the SHA-256 output; shorter
+	// secrets can be brute forced from a single token.
+	minHS256SecretBytes = 32
+)
+
+type header struct {
+	Alg string `json:"alg"`
+	Kid string `json:"kid"`
+	Typ string `json:"typ"`
+}
+
+// Claims is what an access token carries so requests can be authorized
+// without reading the session from the database.
+type Claims struct {
+	Subject   string `json:"sub"`
+	Role      string `json:"role"`
+	SessionID int    `json:"sid"`
+	ID        string `json:"jti"`
+	IssuedAt  int64  `json:"iat"`
+	ExpiresAt int64  `json:"exp"`
+}
+
+type key struct {
+	secret     []byte
+	privateKey ed25519.PrivateKey
+	publicKey  ed25519.PublicKey
+}
+
+// Manager signs with the first configured key and verifies with any of
+// them, selected by the kid header.
+type Manager struct {
+	alg     string
+	signKID string
+	keys    map[string]key
+}
+
+// NewManager returns nil when the JWT auth mode is not enabled. For HS256 a
+// key's secret is used as is and must be at least 32 bytes; for EdDSA it is
+// a base64 Ed25519 seed.
+func NewManager(config util.Configuration) (*Manager, error) {
+	if config.Auth.Mode != util.AuthModeJWT {
+		return nil, nil
+	}
+	if len(config.Auth.JWTKeys) == 0 {
+		return nil, fmt.Errorf("AUTH_JWT_KEYS is required in %s auth mode", util.AuthModeJWT)
+	}
+
+	m := &Manager{alg: config.Auth.JWTAlgorithm, signKID: config.Auth.JWTKeys[0].ID, keys: map[string]key{}}
+	for _, k := range config.Auth.JWTKeys {
+		switch m.alg {
+		case AlgHS256:
+			if len(k.Secret) < minHS256SecretBytes {
+				return nil, fmt.Errorf("jwt key %q must be at least %d bytes for %s", k.ID, minHS256SecretBytes, AlgHS256)
+			}
+			m.keys[k.ID] = key{secret: []byte(k.Secret)}
+		case AlgEdDSA:
+			seed, err := base64.StdEncoding.DecodeString(k.Secret)
+			if err != nil || len(seed) != ed25519.SeedSize {
+				return nil, fmt.Errorf("jwt key %q must be a base64 %d-byte Ed25519 seed", k.ID, ed25519.SeedSize)
+			}
+			privateKey := ed25519.NewKeyFromSeed(seed)
+			m.keys[k.ID] = key{privateKey: privateKey, publicKey: privateKey.Public().(ed25519.PublicKey)}
+		default:
+			return nil, fmt.Errorf("unsupported jwt algorithm %q", m.alg)
+		}
+	}
+	return m, nil
+}
+
+func (m *Manager) Sign(claims Claims) (string, error) {
+	headerJSON, err := json.Marshal(header{Alg: m.alg, Kid: m.signKID, Typ: "JWT"})
+	if err != nil {
+		return "", err
+	}
+	claimsJSON, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := encode(headerJSON) + "." + encode(claimsJSON)
+	return signingInput + "." + encode(m.sign(m.keys[m.signKID], signingInput)), nil
+}
+
+// Verify checks the signature and expiry. The algorithm is fixed by the
+// configuration, never taken from the token header.
+func (m *Manager) Verify(token string) (*Claims, error) {
+	parts := strings.Split(token, ".")
+	if len(parts) != 3 {
+		return nil, ErrInvalidToken
+	}
+
+	var h header
+	if err := decodeJSON(parts[0], &h); err != nil || h.Alg != m.alg {
+		return nil, ErrInvalidToken
+	}
+	k, ok := m.keys[h.Kid]
+	if !ok {
+		return nil, ErrInvalidToken
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(parts[2])
+	if err != nil || !m.verify(k, parts[0]+"."+parts[1], signature) {
+		return nil, ErrInvalidToken
+	}
+
+	var claims Claims
+	if err := decodeJSON(parts[1], &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrExpiredToken
+	}
+	return &claims, nil
+}
+
+func (m *Manager) sign(k key, signingInput string) []byte {
+	if m.alg == AlgEdDSA {
+		return ed25519.Sign(k.privateKey, []byte(signingInput))
+	}
+	mac := hmac.New(sha256.New, k.secret)
+	mac.Write([]byte(signingInput))
+	return mac.Sum(nil)
+}
+
+func (m *Manager) verify(k key, signingInput string, signature []byte) bool {
+	if m.alg == AlgEdDSA {
+		return ed25519.Verify(k.publicKey, []byte(signingInput), signature)
+	}
+	return hmac.Equal(m.sign(k, signingInput), signature)
+}
+
+func encode(data []byte) string {
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+func decodeJSON(segment string, v interface{}) error {
+	data, err := base64.RawURLEncoding.DecodeString(segment)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, v)
+}
@@ -1,24 +1,46 @@
-package main
-
-import (
-	"ecommerce/wire"
-	"log"
-	"net/http"
-
-	"go.uber.org/zap"
-)
-
-func main() {
-	logger, err := wire.ProvideLogger()
-	if err != nil {
-		log.Panicf("Failed to initialize logger: %v", err)
-	}
-
-	r, err := wire.InitializeRouterHandler()
-	if err != nil {
-		logger.Panic("Failed to initialize router", zap.Error(err))
-	}
-	log.Println("Starting server on port 8080...")
-	log.Fatal(http.ListenAndServe(":8080", r))
-
-}
+package main
+
+import (
+	"context"
+	"ecommerce/wire"
+	"errors"
+	"log"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"go.uber.org/zap"
+)
+
+func main() {
+	logger, err := wire.ProvideLogger()
+	if err != nil {
+		log.Panicf("Failed to initialize logger: %v", err)
+	}
+
+	server, err := wire.InitializeServer()
+	if err != nil {
+		logger.Panic("Failed to initialize router", zap.Error(err))
+	}
+
+	// Background jobs and the HTTP server stop together on SIGINT/SIGTERM.
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	go server.Auth.PurgeSessionsEvery(ctx, server.Config.Auth.SessionPurgeInterval, func(err error) {
+		logger.Error("Failed to purge expired sessions", zap.Error(err))
+	})
+	go server.Auth.SyncRevocationsEvery(ctx, server.Config.Auth.RevocationSyncInterval)
+
+	httpServer := &http.Server{Addr: ":8080", Handler: server.Router}
+	go func() {
+		<-ctx.Done()
+		httpServer.Shutdown(context.Background())
+	}()
+
+	log.Println("Starting server on port 8080...")
+	if err := httpServer.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Fatal(err)
+	}
+}
@@ -1,51 +1,86 @@
-package middleware_auth
-
-import (
-	"context"
-	"ecommerce/helper"
-	"ecommerce/service"
-	"net/http"
-
-	"go.uber.org/zap"
-)
-
-type AuthMiddleware struct {
-	AuthService service.AuthService
-	Log         *zap.Logger
-}
-
-func NewAuthMiddleware(authService service.AuthService, logger *zap.Logger) *AuthMiddleware {
-	return &AuthMiddleware{
-		AuthService: authService,
-		Log:         logger,
-	}
-}
-
-func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
-	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		m.Log.Debug("Middleware: Processing request", zap.String("path", r.URL.Path))
-
-		token := r.Header.Get("Authorization")
-		if len(token) > 7 && token[:7] == "Bearer " {
-			token = token[7:]
-		}
-
-		if token == "" {
-			m.Log.Warn("Middleware: Token missing in request")
-			helper.SendJSONResponse(w, http.StatusUnauthorized, "Token required", nil)
-			return
-		}
-
-		userID, err := m.AuthService.VerifyToken(token)
-		if err != nil {
-			m.Log.Warn("Middleware: Invalid or expired token", zap.Error(err))
-			helper.SendJSONResponse(w, http.StatusUnauthorized, "Invalid or expired token", nil)
-			return
-		}
-
-		m.Log.Info("Middleware: Token validated successfully", zap.Int("userID", userID))
-
-		ctx := context.WithValue(r.Context(), "userID", userID)
-		next.ServeHTTP(w, r.WithContext(ctx))
-	})
-}
+package middleware_auth
+
+import (
+	"context"
+	"ecommerce/helper"
+	"ecommerce/service"
+	"net/http"
+
+	"go.uber.org/zap"
+)
+
+type AuthMiddleware struct {
+	AuthService service.AuthService
+	Log         *zap.Logger
+}
+
+func NewAuthMiddleware(authService service.AuthService, logger *zap.Logger) *AuthMiddleware {
+	return &AuthMiddleware{
+		AuthService: authService,
+		Log:         logger,
+	}
+}
+
+// OptionalMiddleware lets requests without a token through as guests. A
+// token that is sent must still be valid, so a signed-in client never gets a
+// guest answer by accident.
+func (m *AuthMiddleware) OptionalMiddleware(next http.Handler) http.Handler {
+	authenticated := m.Middleware(next)
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+		authenticated.ServeHTTP(w, r)
+	})
+}
+
+func (m *AuthMiddleware) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		m.Log.Debug("Middleware: Processing request", zap.String("path", r.URL.Path))
+
+		token := r.Header.Get("Authorization")
+		if len(token) > 7 && token[:7] == "Bearer " {
+			token = token[7:]
+		}
+
+		if token == "" {
+			m.Log.Warn("Middleware: Token missing in request")
+			helper.SendJSONResponse(w, http.StatusUnauthorized, "Token required", nil)
+			return
+		}
+
+		session, err := m.AuthService.VerifyToken(token)
+		if err != nil {
+			m.Log.Warn("Middleware: Invalid or expired token", zap.Error(err))
+			helper.SendJSONResponse(w, http.StatusUnauthorized, "Invalid or expired token", nil)
+			return
+		}
+
+		m.Log.Info("Middleware: Token validated successfully", zap.Int("userID", session.UserID), zap.String("role", session.Role))
+
+		ctx := context.WithValue(r.Context(), "userID", session.UserID)
+		ctx = context.WithValue(ctx, "role", session.Role)
+		ctx = context.WithValue(ctx, "sessionID", session.ID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// RequireRole must be chained after Middleware so the role is already in the
+// request context.
+func (m *AuthMiddleware) RequireRole(roles ...string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			role, _ := r.Context().Value("role").(string)
+			for _, allowed := range roles {
+				if role == allowed {
+					next.ServeHTTP(w, r)
+					return
+				}
+			}
+
+			m.Log.Warn("Middleware: Role not allowed", zap.String("path", r.URL.Path), zap.String("role", role), zap.Strings("allowed", roles))
+			helper.SendJSONResponse(w, http.StatusForbidden, "Forbidden", nil)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+-- Stock ledger per product and per variant.
+-- A row with variant '{}' holds the stock for products without variants.
+
+CREATE TABLE IF NOT EXISTS public.inventories (
+    id serial PRIMARY KEY,
+    product_id integer NOT NULL REFERENCES public.products(id),
+    variant jsonb DEFAULT '{}'::jsonb NOT NULL,
+    quantity integer DEFAULT 0 NOT NULL,
+    reserved integer DEFAULT 0 NOT NULL,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT inventories_quantity_check CHECK (quantity >= 0),
+    CONSTRAINT inventories_reserved_check CHECK (reserved >= 0 AND reserved <= quantity),
+    CONSTRAINT inventories_product_id_variant_key UNIQUE (product_id, variant)
+);
+
+CREATE TABLE IF NOT EXISTS public.stock_movements (
+    id serial PRIMARY KEY,
+    inventory_id integer NOT NULL REFERENCES public.inventories(id),
+    order_id integer REFERENCES public.orders(id),
+    change integer NOT NULL,
+    reason character varying(20) NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+-- Seed stock for the sample catalogue.
+INSERT INTO public.inventories (product_id, quantity)
+SELECT id, 100 FROM public.products
+ON CONFLICT (product_id, variant) DO NOTHING;
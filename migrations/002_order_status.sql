@@ -0,0 +1,24 @@
+-- Order lifecycle: pending -> paid -> processing -> shipped -> delivered,
+-- plus cancelled and refunded.
+
+ALTER TABLE public.orders DROP CONSTRAINT IF EXISTS orders_status_check;
+
+UPDATE public.orders SET status = 'delivered' WHERE status = 'completed';
+UPDATE public.orders SET status = 'cancelled' WHERE status = 'canceled';
+
+ALTER TABLE public.orders
+    ADD CONSTRAINT orders_status_check CHECK (((status)::text = ANY ((ARRAY['pending'::character varying, 'paid'::character varying, 'processing'::character varying, 'shipped'::character varying, 'delivered'::character varying, 'cancelled'::character varying, 'refunded'::character varying])::text[])));
+
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP;
+
+CREATE TABLE IF NOT EXISTS public.order_status_histories (
+    id serial PRIMARY KEY,
+    order_id integer NOT NULL REFERENCES public.orders(id),
+    from_status character varying(20),
+    to_status character varying(20) NOT NULL,
+    changed_by integer REFERENCES public.users(id),
+    note character varying(255),
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS order_status_histories_order_id_idx ON public.order_status_histories (order_id);
@@ -0,0 +1,6 @@
+-- Reviews are listed newest first and can be edited by their author.
+
+ALTER TABLE public.ratings ADD COLUMN IF NOT EXISTS created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE public.ratings ADD COLUMN IF NOT EXISTS updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP;
+
+CREATE INDEX IF NOT EXISTS ratings_product_id_idx ON public.ratings (product_id);
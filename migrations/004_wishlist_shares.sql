@@ -0,0 +1,8 @@
+-- Read-only share links for a user's wishlist.
+
+CREATE TABLE IF NOT EXISTS public.wishlist_shares (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL UNIQUE REFERENCES public.users(id) ON DELETE CASCADE,
+    token character varying(64) NOT NULL UNIQUE,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,7 @@
+-- Soft-delete for the catalogue so historical order_items keep resolving.
+
+ALTER TABLE public.products ADD COLUMN IF NOT EXISTS updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP;
+ALTER TABLE public.products ADD COLUMN IF NOT EXISTS deleted_at timestamp without time zone;
+ALTER TABLE public.categories ADD COLUMN IF NOT EXISTS deleted_at timestamp without time zone;
+
+CREATE INDEX IF NOT EXISTS products_active_idx ON public.products (id) WHERE deleted_at IS NULL;
@@ -0,0 +1,10 @@
+-- Role-based access control: customer, staff and admin.
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS role character varying(20) DEFAULT 'customer'::character varying NOT NULL;
+
+ALTER TABLE public.users DROP CONSTRAINT IF EXISTS users_role_check;
+ALTER TABLE public.users
+    ADD CONSTRAINT users_role_check CHECK (((role)::text = ANY ((ARRAY['customer'::character varying, 'staff'::character varying, 'admin'::character varying])::text[])));
+
+-- Bootstrap the first admin from the sample data.
+UPDATE public.users SET role = 'admin' WHERE id = 1;
@@ -0,0 +1,26 @@
+-- Payments linked to orders, plus processed webhook events for idempotency.
+
+CREATE TABLE IF NOT EXISTS public.payments (
+    id serial PRIMARY KEY,
+    order_id integer NOT NULL REFERENCES public.orders(id),
+    provider character varying(30) NOT NULL,
+    provider_ref character varying(100) NOT NULL,
+    amount numeric(10,2) NOT NULL,
+    status character varying(20) DEFAULT 'pending'::character varying NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT payments_status_check CHECK (((status)::text = ANY ((ARRAY['pending'::character varying, 'authorized'::character varying, 'succeeded'::character varying, 'failed'::character varying, 'refunded'::character varying])::text[]))),
+    UNIQUE (provider, provider_ref)
+);
+
+CREATE INDEX IF NOT EXISTS payments_order_id_idx ON public.payments (order_id);
+
+CREATE TABLE IF NOT EXISTS public.payment_events (
+    id serial PRIMARY KEY,
+    provider character varying(30) NOT NULL,
+    event_id character varying(100) NOT NULL,
+    payment_id integer NOT NULL REFERENCES public.payments(id),
+    type character varying(50) NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (provider, event_id)
+);
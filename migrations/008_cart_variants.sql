@@ -0,0 +1,8 @@
+-- Selected variant (e.g. {"size": "M", "color": "red"}) on cart and order
+-- lines. A cart may hold one line per product variant.
+
+ALTER TABLE public.order_items ADD COLUMN IF NOT EXISTS variant jsonb DEFAULT '{}'::jsonb NOT NULL;
+
+ALTER TABLE public.order_items DROP CONSTRAINT IF EXISTS unique_cart_item;
+DROP INDEX IF EXISTS public.unique_cart_item;
+CREATE UNIQUE INDEX unique_cart_item ON public.order_items (user_id, product_id, variant) WHERE order_id IS NULL;
@@ -0,0 +1,48 @@
+-- Carts get their own tables. order_items now only holds lines of placed
+-- orders, with the product name and image snapshotted at order time.
+
+CREATE TABLE IF NOT EXISTS public.carts (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL UNIQUE REFERENCES public.users(id),
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS public.cart_items (
+    id serial PRIMARY KEY,
+    cart_id integer NOT NULL REFERENCES public.carts(id) ON DELETE CASCADE,
+    product_id integer NOT NULL REFERENCES public.products(id),
+    variant jsonb DEFAULT '{}'::jsonb NOT NULL,
+    quantity integer NOT NULL CHECK (quantity > 0),
+    price numeric(10,2) NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    UNIQUE (cart_id, product_id, variant)
+);
+
+-- Move open cart lines out of order_items.
+INSERT INTO public.carts (user_id)
+SELECT DISTINCT user_id FROM public.order_items WHERE order_id IS NULL AND user_id IS NOT NULL
+ON CONFLICT (user_id) DO NOTHING;
+
+INSERT INTO public.cart_items (cart_id, product_id, variant, quantity, price)
+SELECT c.id, oi.product_id, oi.variant, oi.quantity, oi.price
+FROM public.order_items oi
+JOIN public.carts c ON c.user_id = oi.user_id
+WHERE oi.order_id IS NULL
+ON CONFLICT (cart_id, product_id, variant) DO NOTHING;
+
+DELETE FROM public.order_items WHERE order_id IS NULL;
+
+DROP INDEX IF EXISTS public.unique_cart_item;
+ALTER TABLE public.order_items DROP CONSTRAINT IF EXISTS unique_cart_item;
+
+ALTER TABLE public.order_items ADD COLUMN IF NOT EXISTS product_name character varying(255);
+ALTER TABLE public.order_items ADD COLUMN IF NOT EXISTS image text;
+
+UPDATE public.order_items oi
+SET product_name = p.name, image = p.images->>0
+FROM public.products p
+WHERE p.id = oi.product_id AND oi.product_name IS NULL;
+
+ALTER TABLE public.order_items ALTER COLUMN order_id SET NOT NULL;
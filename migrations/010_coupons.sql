@@ -0,0 +1,37 @@
+-- Promo codes: percentage, fixed amount or free shipping, optionally scoped
+-- to products and/or categories.
+
+CREATE TABLE IF NOT EXISTS public.coupons (
+    id serial PRIMARY KEY,
+    code character varying(30) NOT NULL,
+    type character varying(20) NOT NULL,
+    value numeric(10,2) DEFAULT 0 NOT NULL,
+    min_spend numeric(10,2) DEFAULT 0 NOT NULL,
+    max_discount numeric(10,2),
+    usage_limit integer,
+    per_user_limit integer,
+    starts_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    ends_at timestamp without time zone NOT NULL,
+    product_ids integer[] DEFAULT '{}'::integer[] NOT NULL,
+    category_ids integer[] DEFAULT '{}'::integer[] NOT NULL,
+    active boolean DEFAULT true NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT coupons_type_check CHECK (((type)::text = ANY ((ARRAY['percentage'::character varying, 'fixed'::character varying, 'free_shipping'::character varying])::text[]))),
+    CONSTRAINT coupons_check CHECK ((ends_at > starts_at))
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS coupons_code_key ON public.coupons (upper((code)::text));
+
+CREATE TABLE IF NOT EXISTS public.coupon_redemptions (
+    id serial PRIMARY KEY,
+    coupon_id integer NOT NULL REFERENCES public.coupons(id),
+    user_id integer NOT NULL REFERENCES public.users(id),
+    order_id integer NOT NULL UNIQUE REFERENCES public.orders(id),
+    discount numeric(10,2) NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS coupon_redemptions_coupon_id_idx ON public.coupon_redemptions (coupon_id);
+
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS coupon_code character varying(30);
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS discount_amount numeric(10,2) DEFAULT 0 NOT NULL;
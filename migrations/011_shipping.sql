@@ -0,0 +1,9 @@
+-- Shipping is priced per order: product weight in grams, the chosen method
+-- stored in orders.shipping and its cost in orders.shipping_cost.
+
+ALTER TABLE public.products ADD COLUMN IF NOT EXISTS weight integer DEFAULT 1000 NOT NULL;
+ALTER TABLE public.products DROP CONSTRAINT IF EXISTS products_weight_check;
+ALTER TABLE public.products ADD CONSTRAINT products_weight_check CHECK ((weight > 0));
+
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS shipping_cost numeric(10,2) DEFAULT 0 NOT NULL;
+ALTER TABLE public.orders ALTER COLUMN shipping SET DEFAULT 'regular'::character varying;
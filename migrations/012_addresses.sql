@@ -0,0 +1,43 @@
+-- Addresses move out of the users.address jsonb array into their own table
+-- so every address keeps a stable id. Orders reference the address they were
+-- placed with and keep a copy of it, so editing or deleting an address later
+-- does not change where an order was shipped.
+
+CREATE TABLE IF NOT EXISTS public.addresses (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    label character varying(50) DEFAULT ''::character varying NOT NULL,
+    recipient character varying(255) NOT NULL,
+    phone character varying(20) DEFAULT ''::character varying NOT NULL,
+    street text NOT NULL,
+    city character varying(100) DEFAULT ''::character varying NOT NULL,
+    province character varying(100) DEFAULT ''::character varying NOT NULL,
+    postal_code character varying(10) DEFAULT ''::character varying NOT NULL,
+    created_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP,
+    updated_at timestamp without time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS addresses_user_id_idx ON public.addresses (user_id);
+
+-- The old free-text entries become the street line, in their array order.
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'users' AND column_name = 'address'
+    ) THEN
+        INSERT INTO public.addresses (user_id, recipient, phone, street)
+        SELECT u.id, u.name, COALESCE(u.phone, ''), a.street
+        FROM public.users u
+        CROSS JOIN LATERAL jsonb_array_elements_text(u.address) WITH ORDINALITY AS a(street, position)
+        WHERE btrim(a.street) <> ''
+        ORDER BY u.id, a.position;
+
+        ALTER TABLE public.users DROP CONSTRAINT IF EXISTS address_is_array;
+        ALTER TABLE public.users DROP COLUMN address;
+    END IF;
+END $$;
+
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS address_id integer REFERENCES public.addresses(id) ON DELETE SET NULL;
+ALTER TABLE public.orders ADD COLUMN IF NOT EXISTS shipping_address_detail jsonb;
+ALTER TABLE public.orders ALTER COLUMN shipping_address TYPE text;
@@ -0,0 +1,8 @@
+-- Each user keeps one default address. Deleting it clears the column; the
+-- application then promotes the user's oldest remaining address.
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS default_address_id integer REFERENCES public.addresses(id) ON DELETE SET NULL;
+
+UPDATE public.users u
+SET default_address_id = (SELECT a.id FROM public.addresses a WHERE a.user_id = u.id ORDER BY a.id ASC LIMIT 1)
+WHERE u.default_address_id IS NULL;
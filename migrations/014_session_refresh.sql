@@ -0,0 +1,22 @@
+-- A session now outlives its access token. sessions.token/expires_at hold the
+-- short-lived access token; refresh_tokens holds single-use tokens that renew
+-- it. Every refresh rotates the refresh token, and presenting a used one again
+-- revokes the whole session.
+
+ALTER TABLE public.sessions ADD COLUMN IF NOT EXISTS user_agent text DEFAULT ''::text NOT NULL;
+ALTER TABLE public.sessions ADD COLUMN IF NOT EXISTS ip_address character varying(45) DEFAULT ''::character varying NOT NULL;
+ALTER TABLE public.sessions ADD COLUMN IF NOT EXISTS last_seen_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL;
+ALTER TABLE public.sessions ADD COLUMN IF NOT EXISTS revoked_at timestamp with time zone;
+
+CREATE INDEX IF NOT EXISTS sessions_user_id_idx ON public.sessions (user_id);
+
+CREATE TABLE IF NOT EXISTS public.refresh_tokens (
+    id serial PRIMARY KEY,
+    session_id integer NOT NULL REFERENCES public.sessions(id) ON DELETE CASCADE,
+    token text NOT NULL UNIQUE,
+    expires_at timestamp with time zone NOT NULL,
+    used_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS refresh_tokens_session_id_idx ON public.refresh_tokens (session_id);
@@ -0,0 +1,22 @@
+-- Bearer tokens are no longer stored: sessions and refresh_tokens keep the
+-- hex SHA-256 of the token instead. Existing rows are hashed in place, so
+-- clients that are already logged in keep working.
+
+DO $$
+BEGIN
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'sessions' AND column_name = 'token'
+    ) THEN
+        ALTER TABLE public.sessions RENAME COLUMN token TO token_hash;
+        UPDATE public.sessions SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+    END IF;
+
+    IF EXISTS (
+        SELECT 1 FROM information_schema.columns
+        WHERE table_schema = 'public' AND table_name = 'refresh_tokens' AND column_name = 'token'
+    ) THEN
+        ALTER TABLE public.refresh_tokens RENAME COLUMN token TO token_hash;
+        UPDATE public.refresh_tokens SET token_hash = encode(sha256(convert_to(token_hash, 'UTF8')), 'hex');
+    END IF;
+END $$;
@@ -0,0 +1,5 @@
+-- Logout revokes a session instead of deleting it. Revoked sessions whose
+-- access token is still valid form the revocation list that JWT mode
+-- reloads periodically.
+
+CREATE INDEX IF NOT EXISTS sessions_revoked_idx ON public.sessions (expires_at) WHERE revoked_at IS NOT NULL;
@@ -0,0 +1,18 @@
+-- Contact verification and password reset. Codes are stored hashed, expire,
+-- and stop working after too many wrong attempts.
+
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS email_verified_at timestamp with time zone;
+ALTER TABLE public.users ADD COLUMN IF NOT EXISTS phone_verified_at timestamp with time zone;
+
+CREATE TABLE IF NOT EXISTS public.verification_codes (
+    id serial PRIMARY KEY,
+    user_id integer NOT NULL REFERENCES public.users(id) ON DELETE CASCADE,
+    purpose character varying(20) NOT NULL CHECK (purpose IN ('verify_email', 'verify_phone', 'reset_password')),
+    code_hash text NOT NULL,
+    attempts integer DEFAULT 0 NOT NULL,
+    expires_at timestamp with time zone NOT NULL,
+    consumed_at timestamp with time zone,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS verification_codes_user_purpose_idx ON public.verification_codes (user_id, purpose);
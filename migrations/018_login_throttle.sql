@@ -0,0 +1,24 @@
+-- Failed logins are counted per login identifier and per client IP. The
+-- identifier is tracked whether or not an account exists for it, so the
+-- throttle itself does not reveal which emails or phones are registered.
+
+CREATE TABLE IF NOT EXISTS public.login_throttles (
+    scope character varying(10) NOT NULL CHECK (scope IN ('account', 'ip')),
+    key text NOT NULL,
+    failures integer DEFAULT 0 NOT NULL,
+    last_failure_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    blocked_until timestamp with time zone,
+    PRIMARY KEY (scope, key)
+);
+
+CREATE TABLE IF NOT EXISTS public.audit_logs (
+    id serial PRIMARY KEY,
+    event character varying(50) NOT NULL,
+    user_id integer REFERENCES public.users(id) ON DELETE SET NULL,
+    identifier text DEFAULT ''::text NOT NULL,
+    ip_address character varying(45) DEFAULT ''::character varying NOT NULL,
+    detail jsonb DEFAULT '{}'::jsonb NOT NULL,
+    created_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS audit_logs_event_created_at_idx ON public.audit_logs (event, created_at);
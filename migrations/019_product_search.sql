@@ -0,0 +1,44 @@
+-- Full-text search over products. search_vector weights the name highest,
+-- then title and category, then subtitle and description, and is kept up to
+-- date by triggers, including when a category is renamed. Trigram indexes
+-- back the typo-tolerant fallback and the "did you mean" suggestions.
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+ALTER TABLE public.products ADD COLUMN IF NOT EXISTS search_vector tsvector;
+
+CREATE OR REPLACE FUNCTION public.products_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    NEW.search_vector :=
+        setweight(to_tsvector('simple', COALESCE(NEW.name, '')), 'A') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.title, '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE((SELECT c.name FROM public.categories c WHERE c.id = NEW.category_id), '')), 'B') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.subtitle, '')), 'C') ||
+        setweight(to_tsvector('simple', COALESCE(NEW.description, '')), 'D');
+    RETURN NEW;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS products_search_vector_trigger ON public.products;
+CREATE TRIGGER products_search_vector_trigger
+    BEFORE INSERT OR UPDATE OF name, title, subtitle, description, category_id ON public.products
+    FOR EACH ROW EXECUTE FUNCTION public.products_search_vector_update();
+
+CREATE OR REPLACE FUNCTION public.categories_search_vector_update() RETURNS trigger AS $$
+BEGIN
+    UPDATE public.products SET name = name WHERE category_id = NEW.id;
+    RETURN NULL;
+END
+$$ LANGUAGE plpgsql;
+
+DROP TRIGGER IF EXISTS categories_search_vector_trigger ON public.categories;
+CREATE TRIGGER categories_search_vector_trigger
+    AFTER UPDATE OF name ON public.categories
+    FOR EACH ROW WHEN (OLD.name IS DISTINCT FROM NEW.name)
+    EXECUTE FUNCTION public.categories_search_vector_update();
+
+UPDATE public.products SET name = name WHERE search_vector IS NULL;
+
+CREATE INDEX IF NOT EXISTS products_search_vector_idx ON public.products USING gin (search_vector);
+CREATE INDEX IF NOT EXISTS products_name_trgm_idx ON public.products USING gin (name gin_trgm_ops);
+CREATE INDEX IF NOT EXISTS products_title_trgm_idx ON public.products USING gin (title gin_trgm_ops);
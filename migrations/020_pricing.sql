@@ -0,0 +1,48 @@
+-- One place decides what a product costs. product_price() resolves the unit
+-- price of a product for a quantity at a point in time: the best weekly
+-- promotion running that day or the best bulk tier reached by the quantity,
+-- whichever discount is larger (they do not stack). Listings, the cart and
+-- checkout all read prices through it; coupons then apply on top of the
+-- resulting line totals.
+
+CREATE TABLE IF NOT EXISTS public.product_price_tiers (
+    id serial PRIMARY KEY,
+    product_id integer NOT NULL REFERENCES public.products(id) ON DELETE CASCADE,
+    min_quantity integer NOT NULL CHECK (min_quantity >= 2),
+    discount_percentage smallint NOT NULL CHECK (discount_percentage BETWEEN 1 AND 100),
+    UNIQUE (product_id, min_quantity)
+);
+
+CREATE INDEX IF NOT EXISTS weekly_promotions_product_dates_idx ON public.weekly_promotions (product_id, start_date, end_date);
+
+CREATE OR REPLACE FUNCTION public.product_price(
+    p_product_id integer,
+    p_quantity integer DEFAULT 1,
+    p_at timestamp with time zone DEFAULT now()
+) RETURNS TABLE (
+    base_price numeric,
+    promotion_percentage integer,
+    tier_percentage integer,
+    discount_percentage integer,
+    unit_price numeric
+) AS $$
+    SELECT p.price,
+        COALESCE(promo.pct, 0),
+        COALESCE(tier.pct, 0),
+        GREATEST(COALESCE(promo.pct, 0), COALESCE(tier.pct, 0)),
+        round(p.price * (100 - GREATEST(COALESCE(promo.pct, 0), COALESCE(tier.pct, 0))) / 100.0, 2)
+    FROM public.products p
+    LEFT JOIN LATERAL (
+        SELECT MAX(wp.discount_percentage)::integer AS pct
+        FROM public.weekly_promotions wp
+        WHERE wp.product_id = p.id AND wp.start_date <= p_at::date AND wp.end_date >= p_at::date
+    ) promo ON TRUE
+    LEFT JOIN LATERAL (
+        SELECT t.discount_percentage::integer AS pct
+        FROM public.product_price_tiers t
+        WHERE t.product_id = p.id AND t.min_quantity <= p_quantity
+        ORDER BY t.min_quantity DESC
+        LIMIT 1
+    ) tier ON TRUE
+    WHERE p.id = p_product_id
+$$ LANGUAGE sql STABLE;
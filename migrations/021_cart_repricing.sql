@@ -0,0 +1,8 @@
+-- Cart lines remember the promotion they were priced with, so repricing can
+-- tell a customer that a promotion ended rather than only that the price
+-- went up.
+
+ALTER TABLE public.cart_items ADD COLUMN IF NOT EXISTS promotion_percentage integer NOT NULL DEFAULT 0;
+
+UPDATE public.cart_items ci
+SET promotion_percentage = COALESCE((SELECT pr.promotion_percentage FROM public.product_price(ci.product_id, ci.quantity, ci.updated_at) pr), 0);
@@ -0,0 +1,11 @@
+-- Issued verification and reset codes are counted per user and per contact
+-- in fixed windows, so codes cannot be reissued without limit to get fresh
+-- attempt budgets.
+
+CREATE TABLE IF NOT EXISTS public.code_request_throttles (
+    scope character varying(10) NOT NULL CHECK (scope IN ('user', 'contact')),
+    key text NOT NULL,
+    requests integer DEFAULT 0 NOT NULL,
+    window_started_at timestamp with time zone DEFAULT CURRENT_TIMESTAMP NOT NULL,
+    PRIMARY KEY (scope, key)
+);
@@ -0,0 +1,6 @@
+-- Percentage coupons are capped at 100%. NOT VALID keeps older rows loadable;
+-- checkout clamps their discount to the eligible subtotal anyway.
+
+ALTER TABLE public.coupons DROP CONSTRAINT IF EXISTS coupons_percentage_value_check;
+ALTER TABLE public.coupons ADD CONSTRAINT coupons_percentage_value_check
+    CHECK (type <> 'percentage' OR (value > 0 AND value <= 100)) NOT VALID;
@@ -0,0 +1,5 @@
+-- Category names only need to be unique among live categories, so a
+-- soft-deleted category no longer blocks reusing its name.
+
+ALTER TABLE public.categories DROP CONSTRAINT IF EXISTS categories_name_key;
+CREATE UNIQUE INDEX IF NOT EXISTS categories_name_active_key ON public.categories (name) WHERE deleted_at IS NULL;
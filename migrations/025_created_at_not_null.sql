@@ -0,0 +1,9 @@
+-- Product listings and order history page on created_at, and a cursor can
+-- neither carry nor seek past a NULL key. Rows missing it are backfilled
+-- with the epoch so they sort as the oldest.
+
+UPDATE public.products SET created_at = 'epoch' WHERE created_at IS NULL;
+ALTER TABLE public.products ALTER COLUMN created_at SET NOT NULL;
+
+UPDATE public.orders SET created_at = 'epoch' WHERE created_at IS NULL;
+ALTER TABLE public.orders ALTER COLUMN created_at SET NOT NULL;
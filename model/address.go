@@ -0,0 +1,33 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+type Address struct {
+	ID         int       `json:"id"`
+	UserID     int       `json:"-"`
+	Label      string    `json:"label,omitempty" validate:"max=50"`
+	Recipient  string    `json:"recipient" validate:"required,max=255"`
+	Phone      string    `json:"phone" validate:"omitempty,numeric,min=10,max=13"`
+	Street     string    `json:"street" validate:"required"`
+	City       string    `json:"city" validate:"required,max=100"`
+	Province   string    `json:"province" validate:"required,max=100"`
+	PostalCode string    `json:"postal_code" validate:"omitempty,numeric,max=10"`
+	IsDefault  bool      `json:"is_default"`
+	CreatedAt  time.Time `json:"created_at"`
+	UpdatedAt  time.Time `json:"updated_at"`
+}
+
+// String joins the non-empty address lines into the single line stored on
+// orders and used to pick a shipping region.
+func (a Address) String() string {
+	var parts []string
+	for _, part := range []string{a.Street, a.City, a.Province, a.PostalCode} {
+		if part = strings.TrimSpace(part); part != "" {
+			parts = append(parts, part)
+		}
+	}
+	return strings.Join(parts, ", ")
+}
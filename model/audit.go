@@ -0,0 +1,17 @@
+package model
+
+import "time"
+
+const (
+	AuditLoginLocked = "login_locked"
+)
+
+type AuditLog struct {
+	ID         int                    `json:"id"`
+	Event      string                 `json:"event"`
+	UserID     *int                   `json:"user_id,omitempty"`
+	Identifier string                 `json:"identifier,omitempty"`
+	IPAddress  string                 `json:"ip_address,omitempty"`
+	Detail     map[string]interface{} `json:"detail,omitempty"`
+	CreatedAt  time.Time              `json:"created_at"`
+}
@@ -0,0 +1,20 @@
+package model
+
+import "time"
+
+type CatalogProduct struct {
+	ID          int         `json:"id"`
+	CategoryID  int         `json:"category_id" validate:"required"`
+	Name        string      `json:"name" validate:"required,max=100"`
+	Title       string      `json:"title" validate:"required,max=100"`
+	Subtitle    string      `json:"subtitle" validate:"required,max=100"`
+	Images      []string    `json:"images" validate:"required,min=1,dive,required"`
+	Description string      `json:"description"`
+	Price       float64     `json:"price" validate:"required,gt=0"`
+	Weight      int         `json:"weight" validate:"omitempty,min=1"`
+	Stock       *int        `json:"stock,omitempty" validate:"omitempty,min=0"`
+	PriceTiers  []PriceTier `json:"price_tiers,omitempty" validate:"omitempty,unique=MinQuantity,dive"`
+	CreatedAt   time.Time   `json:"created_at"`
+	UpdatedAt   time.Time   `json:"updated_at"`
+	DeletedAt   *time.Time  `json:"deleted_at,omitempty"`
+}
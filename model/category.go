@@ -1,6 +1,10 @@
-package model
-
-type Category struct {
-	ID             int      `json:"id"`
-	Name           string   `json:"name"`
-}
\ No newline at end of file
+package model
+
+import "time"
+
+type Category struct {
+	ID        int                 `json:"id"`
+	Name      string              `json:"name" validate:"required,max=50"`
+	Variant   map[string][]string `json:"variant,omitempty" validate:"dive,keys,required,endkeys,required,dive,required"`
+	DeletedAt *time.Time          `json:"deleted_at,omitempty"`
+}
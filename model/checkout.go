@@ -1,32 +1,78 @@
-package model
-
-type Checkout struct {
-	ID         int     `json:"id,omitempty"`
-	UserID     int     `json:"user_id,omitempty"`
-	ProductID  int     `json:"product_id,omitempty"`
-	Name       string  `json:"name,omitempty"`
-	Image      string  `json:"image,omitempty"`
-	Price      float64 `json:"price,omitempty"`
-	Quantity   int     `json:"quantity,omitempty"`
-	TotalPrice float64 `json:"total_price,omitempty"`
-	TotalCarts int     `json:"total_carts,omitempty"`
-}
-
-type OrderResponse struct {
-	OrderID         int         `json:"order_id"`
-	Items           []OrderItem `json:"items"`
-	ShippingAddress *string     `json:"shipping_address"`
-	AddressIndex    int         `json:"-"`
-	Shipping        string      `json:"shipping"`
-	TotalAmount     float64     `json:"total_amount"`
-}
-
-type OrderItem struct {
-	UserID        int     `json:"user_id,omitempty"`
-	ProductID     []int   `json:"product_id,omitempty"`
-	AddressIndex  int     `json:"address_index,omitempty"`
-	ProductName   string  `json:"product_name"`
-	Image         string  `json:"image"`
-	Quantity      int     `json:"quantity,omitempty"`
-	SubtotalPrice float64 `json:"subtotal_price"`
-}
+package model
+
+import "time"
+
+const (
+	OrderStatusPending    = "pending"
+	OrderStatusPaid       = "paid"
+	OrderStatusProcessing = "processing"
+	OrderStatusShipped    = "shipped"
+	OrderStatusDelivered  = "delivered"
+	OrderStatusCancelled  = "cancelled"
+	OrderStatusRefunded   = "refunded"
+)
+
+type Checkout struct {
+	ID         int               `json:"id,omitempty"`
+	UserID     int               `json:"user_id,omitempty"`
+	ProductID  int               `json:"product_id,omitempty"`
+	Name       string            `json:"name,omitempty"`
+	Image      string            `json:"image,omitempty"`
+	Variant    map[string]string `json:"variant,omitempty"`
+	Price      float64           `json:"price,omitempty"`
+	Quantity   int               `json:"quantity,omitempty"`
+	TotalPrice float64           `json:"total_price,omitempty"`
+	TotalCarts int               `json:"total_carts,omitempty"`
+	Notice     *CartNotice       `json:"notice,omitempty"`
+}
+
+const (
+	CartNoticePriceIncreased = "price_increased"
+	CartNoticePriceDecreased = "price_decreased"
+	CartNoticePromotionEnded = "promotion_ended"
+	CartNoticeUnavailable    = "unavailable"
+)
+
+// CartNotice tells the customer that a cart line no longer costs what it did
+// when it was added, or can no longer be bought.
+type CartNotice struct {
+	CartItemID int     `json:"cart_item_id"`
+	ProductID  int     `json:"product_id"`
+	Name       string  `json:"name"`
+	Type       string  `json:"type"`
+	OldPrice   float64 `json:"old_price"`
+	NewPrice   float64 `json:"new_price"`
+}
+
+type OrderResponse struct {
+	OrderID               int                  `json:"order_id"`
+	Items                 []OrderItem          `json:"items"`
+	ShippingAddress       *string              `json:"shipping_address"`
+	ShippingAddressDetail *Address             `json:"shipping_address_detail,omitempty"`
+	Shipping              string               `json:"shipping"`
+	ShippingCost          float64              `json:"shipping_cost"`
+	CouponCode            *string              `json:"coupon_code,omitempty"`
+	DiscountAmount        float64              `json:"discount_amount"`
+	TotalAmount           float64              `json:"total_amount"`
+	Status                string               `json:"status"`
+	CreatedAt             time.Time            `json:"created_at"`
+	StatusHistory         []OrderStatusHistory `json:"status_history,omitempty"`
+}
+
+type OrderStatusHistory struct {
+	FromStatus *string   `json:"from_status"`
+	ToStatus   string    `json:"to_status"`
+	ChangedBy  *int      `json:"changed_by,omitempty"`
+	Note       *string   `json:"note,omitempty"`
+	CreatedAt  time.Time `json:"created_at"`
+}
+
+type OrderItem struct {
+	UserID        int               `json:"user_id,omitempty"`
+	ProductID     []int             `json:"product_id,omitempty"`
+	ProductName   string            `json:"product_name"`
+	Image         string            `json:"image"`
+	Variant       map[string]string `json:"variant,omitempty"`
+	Quantity      int               `json:"quantity,omitempty"`
+	SubtotalPrice float64           `json:"subtotal_price"`
+}
@@ -0,0 +1,38 @@
+package model
+
+import "time"
+
+const (
+	CouponTypePercentage   = "percentage"
+	CouponTypeFixed        = "fixed"
+	CouponTypeFreeShipping = "free_shipping"
+)
+
+type Coupon struct {
+	ID           int       `json:"id"`
+	Code         string    `json:"code" validate:"required,max=30"`
+	Type         string    `json:"type" validate:"required,oneof=percentage fixed free_shipping"`
+	Value        float64   `json:"value" validate:"gte=0"`
+	MinSpend     float64   `json:"min_spend" validate:"gte=0"`
+	MaxDiscount  *float64  `json:"max_discount,omitempty" validate:"omitempty,gt=0"`
+	UsageLimit   *int      `json:"usage_limit,omitempty" validate:"omitempty,min=1"`
+	PerUserLimit *int      `json:"per_user_limit,omitempty" validate:"omitempty,min=1"`
+	StartsAt     time.Time `json:"starts_at"`
+	EndsAt       time.Time `json:"ends_at" validate:"required,gtfield=StartsAt"`
+	ProductIDs   []int     `json:"product_ids"`
+	CategoryIDs  []int     `json:"category_ids"`
+	Active       bool      `json:"active"`
+	TimesUsed    int       `json:"times_used"`
+	CreatedAt    time.Time `json:"created_at"`
+}
+
+type CouponPreview struct {
+	CouponID         int     `json:"-"`
+	Code             string  `json:"code"`
+	Type             string  `json:"type"`
+	Subtotal         float64 `json:"subtotal"`
+	EligibleSubtotal float64 `json:"eligible_subtotal"`
+	Discount         float64 `json:"discount"`
+	FreeShipping     bool    `json:"free_shipping"`
+	Total            float64 `json:"total"`
+}
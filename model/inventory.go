@@ -0,0 +1,6 @@
+package model
+
+type VariantStock struct {
+	Variant map[string]string `json:"variant"`
+	Stock   int               `json:"stock"`
+}
@@ -0,0 +1,14 @@
+package model
+
+// Pagination describes one page of a listing. Offset paging uses Page and
+// fills TotalItems and TotalPages; cursor paging starts from Cursor and skips
+// the count. Both fill NextCursor and PrevCursor when there is such a page.
+type Pagination struct {
+	Page       int
+	Limit      int
+	Cursor     string
+	TotalItems int
+	TotalPages int
+	NextCursor string
+	PrevCursor string
+}
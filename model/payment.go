@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+const (
+	PaymentStatusPending    = "pending"
+	PaymentStatusAuthorized = "authorized"
+	PaymentStatusSucceeded  = "succeeded"
+	PaymentStatusFailed     = "failed"
+	PaymentStatusRefunded   = "refunded"
+)
+
+type Payment struct {
+	ID           int       `json:"id"`
+	OrderID      int       `json:"order_id"`
+	Provider     string    `json:"provider"`
+	ProviderRef  string    `json:"provider_ref"`
+	Amount       float64   `json:"amount"`
+	Status       string    `json:"status"`
+	ClientSecret string    `json:"client_secret,omitempty"`
+	RedirectURL  string    `json:"redirect_url,omitempty"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+// PriceTier gives a bulk discount once a cart line reaches MinQuantity.
+type PriceTier struct {
+	MinQuantity        int `json:"min_quantity" validate:"min=2"`
+	DiscountPercentage int `json:"discount_percentage" validate:"min=1,max=100"`
+}
+
+// PriceQuote is what a quantity of a product costs at a point in time. The
+// larger of the promotion and tier discounts applies; a coupon, when given,
+// is priced on top of the subtotal.
+type PriceQuote struct {
+	ProductID           int            `json:"product_id"`
+	Quantity            int            `json:"quantity"`
+	At                  time.Time      `json:"at"`
+	BasePrice           float64        `json:"base_price"`
+	PromotionPercentage int            `json:"promotion_percentage"`
+	TierPercentage      int            `json:"tier_percentage"`
+	DiscountPercentage  int            `json:"discount_percentage"`
+	UnitPrice           float64        `json:"unit_price"`
+	Subtotal            float64        `json:"subtotal"`
+	Coupon              *CouponPreview `json:"coupon,omitempty"`
+	Total               float64        `json:"total"`
+}
@@ -1,26 +1,93 @@
-package model
-
-type Product struct {
-	ID             int         `json:"id"`
-	Name           string      `json:"name"`
-	ThumbnailImage string      `json:"thumbnail_image"`
-	Price          float64     `json:"price"`
-	Discount       float64     `json:"discount_percentage"`
-	DiscountPrice  float64     `json:"discount_price"`
-	AverageRating  float64     `json:"average_rating"`
-	Sold           int         `json:"sold"`
-	IsNEW          bool        `json:"is_new"`
-}
-type ProductID struct {
-	ID             int         `json:"id"`
-	Name           string      `json:"name"`
-	Images         []string    `json:"images"`
-	Category       string      `json:"category_name"`
-	Price          float64     `json:"price"`
-	Variant        interface{} `json:"variant"`
-	Discount       float64     `json:"discount_percentage"`
-	DiscountPrice  float64     `json:"discount_price"`
-	AverageRating  float64     `json:"average_rating"`
-	Sold           int         `json:"sold"`
-	IsNEW          bool        `json:"is_new"`
-}
+package model
+
+type Product struct {
+	ID             int     `json:"id"`
+	Name           string  `json:"name"`
+	ThumbnailImage string  `json:"thumbnail_image"`
+	Price          float64 `json:"price"`
+	Discount       float64 `json:"discount_percentage"`
+	DiscountPrice  float64 `json:"discount_price"`
+	AverageRating  float64 `json:"average_rating"`
+	Sold           int     `json:"sold"`
+	IsNEW          bool    `json:"is_new"`
+}
+type ProductID struct {
+	ID            int            `json:"id"`
+	Name          string         `json:"name"`
+	Images        []string       `json:"images"`
+	Category      string         `json:"category_name"`
+	Price         float64        `json:"price"`
+	Variant       interface{}    `json:"variant"`
+	Discount      float64        `json:"discount_percentage"`
+	DiscountPrice float64        `json:"discount_price"`
+	AverageRating float64        `json:"average_rating"`
+	Sold          int            `json:"sold"`
+	IsNEW         bool           `json:"is_new"`
+	Stock         int            `json:"stock"`
+	VariantStocks []VariantStock `json:"variant_stocks,omitempty"`
+	PriceTiers    []PriceTier    `json:"price_tiers,omitempty"`
+}
+
+const (
+	SortPriceAsc    = "price_asc"
+	SortPriceDesc   = "price_desc"
+	SortNewest      = "newest"
+	SortRating      = "rating"
+	SortBestSelling = "best_selling"
+)
+
+// ProductFilter narrows the product listing. Zero values mean "no filter".
+// Prices are compared against the discounted price while a promotion runs.
+type ProductFilter struct {
+	Name        string
+	CategoryID  int
+	MinPrice    float64
+	MaxPrice    float64
+	MinRating   float64
+	OnPromotion bool
+	IsNew       bool
+	Variant     map[string]string
+	Sort        string
+}
+
+// ProductFacets counts the listing by category, price range and rating. Each
+// facet applies every filter except its own, so the counts show what
+// selecting another option would return.
+type ProductFacets struct {
+	Categories  []CategoryFacet   `json:"categories"`
+	PriceRanges []PriceRangeFacet `json:"price_ranges"`
+	Ratings     []RatingFacet     `json:"ratings"`
+}
+
+type CategoryFacet struct {
+	ID    int    `json:"id"`
+	Name  string `json:"name"`
+	Count int    `json:"count"`
+}
+
+// PriceRangeFacet covers Min up to but not including Max; the last range has
+// no Max.
+type PriceRangeFacet struct {
+	Min   float64  `json:"min"`
+	Max   *float64 `json:"max"`
+	Count int      `json:"count"`
+}
+
+type RatingFacet struct {
+	MinRating int `json:"min_rating"`
+	Count     int `json:"count"`
+}
+
+// SearchProduct is a search hit. Relevance is the full-text rank, or zero
+// for hits that only matched the typo-tolerant fallback.
+type SearchProduct struct {
+	Product
+	Relevance float64 `json:"relevance"`
+	Snippet   string  `json:"snippet"`
+}
+
+type ProductSearch struct {
+	Query       string           `json:"query"`
+	Items       []*SearchProduct `json:"items"`
+	Suggestions []string         `json:"suggestions,omitempty"`
+}
@@ -1,11 +1,14 @@
-package model
-
-type Response struct {
-	Status     int         `json:"status_code"`
-	Message    string      `json:"message,omitempty"`
-	Page       int         `json:"page,omitempty"`
-	Limit      int         `json:"limit,omitempty"`
-	TotalItems int         `json:"total_items,omitempty"`
-	TotalPages int         `json:"total_pages,omitempty"`
-	Data       interface{} `json:"data,omitempty"`
+package model
+
+type Response struct {
+	Status     int         `json:"status_code"`
+	Message    string      `json:"message,omitempty"`
+	Page       int         `json:"page,omitempty"`
+	Limit      int         `json:"limit,omitempty"`
+	TotalItems int         `json:"total_items,omitempty"`
+	TotalPages int         `json:"total_pages,omitempty"`
+	NextCursor string      `json:"next_cursor,omitempty"`
+	PrevCursor string      `json:"prev_cursor,omitempty"`
+	Data       interface{} `json:"data,omitempty"`
+	Facets     interface{} `json:"facets,omitempty"`
 }
\ No newline at end of file
@@ -0,0 +1,26 @@
+package model
+
+import "time"
+
+type Review struct {
+	ID        int       `json:"id"`
+	OrderID   int       `json:"order_id,omitempty"`
+	ProductID int       `json:"product_id,omitempty"`
+	UserID    int       `json:"user_id,omitempty"`
+	UserName  string    `json:"user_name,omitempty"`
+	Rating    int       `json:"rating" validate:"required,min=1,max=5"`
+	Review    string    `json:"review,omitempty" validate:"max=255"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+type ReviewSummary struct {
+	AverageRating float64     `json:"average_rating"`
+	TotalReviews  int         `json:"total_reviews"`
+	Histogram     map[int]int `json:"histogram"`
+}
+
+type ProductReviews struct {
+	Summary ReviewSummary `json:"summary"`
+	Reviews []*Review     `json:"reviews"`
+}
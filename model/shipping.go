@@ -0,0 +1,34 @@
+package model
+
+type ShippingMethod struct {
+	Code          string `json:"code"`
+	Name          string `json:"name"`
+	EstimatedDays string `json:"estimated_days"`
+}
+
+type ShippingQuote struct {
+	Method        string  `json:"method"`
+	Name          string  `json:"name"`
+	Region        string  `json:"region"`
+	WeightGrams   int     `json:"weight_grams"`
+	Cost          float64 `json:"cost"`
+	FreeShipping  bool    `json:"free_shipping"`
+	EstimatedDays string  `json:"estimated_days"`
+}
+
+// CheckoutSummary is what shipping is priced on: the selected cart lines
+// and the address they are sent to.
+type CheckoutSummary struct {
+	AddressID   int
+	Address     string
+	Subtotal    float64
+	WeightGrams int
+}
+
+type CreateOrderRequest struct {
+	UserID         int    `json:"-"`
+	ProductID      []int  `json:"product_id"`
+	AddressID      int    `json:"address_id"`
+	CouponCode     string `json:"coupon_code"`
+	ShippingMethod string `json:"shipping_method"`
+}
@@ -1,23 +1,64 @@
-package model
-
-import (
-	"time"
-)
-
-type User struct {
-	ID             int       `json:"id,omitempty"`
-	Name           string    `json:"name,omitempty" validate:"required,min=3,regex=^[A-Za-z ]+$"`
-	Email          string    `json:"email,omitempty" validate:"required_without=Phone,omitempty,email"`
-	Phone          string    `json:"phone,omitempty" validate:"required_without=Email,omitempty,numeric,min=10,max=13"`
-	Address        []string  `json:"address,omitempty"`
-	DefaultAddress string    `json:"address_default,omitempty"`
-	Password       string    `json:"password,omitempty" validate:"required,min=8"`
-	Token          string    `json:"token,omitempty"`
-	UpdatedAt      time.Time `json:"-"`
-}
-
-type Session struct {
-	UserID    int       `json:"user_id"`
-	Token     string    `json:"token"`
-	ExpiresAt time.Time `json:"expires_at"`
-}
+package model
+
+import (
+	"time"
+)
+
+const (
+	RoleCustomer = "customer"
+	RoleStaff    = "staff"
+	RoleAdmin    = "admin"
+)
+
+const (
+	PurposeVerifyEmail   = "verify_email"
+	PurposeVerifyPhone   = "verify_phone"
+	PurposeResetPassword = "reset_password"
+)
+
+// Failed logins are throttled per login identifier and per client IP.
+const (
+	LoginScopeAccount = "account"
+	LoginScopeIP      = "ip"
+)
+
+// Issuing verification and reset codes is throttled per user and per
+// contact the code is sent to.
+const (
+	CodeScopeUser    = "user"
+	CodeScopeContact = "contact"
+)
+
+type User struct {
+	ID             int       `json:"id,omitempty"`
+	Name           string    `json:"name,omitempty" validate:"required,min=3,regex=^[A-Za-z ]+$"`
+	Email          string    `json:"email,omitempty" validate:"required_without=Phone,omitempty,email"`
+	Phone          string    `json:"phone,omitempty" validate:"required_without=Email,omitempty,numeric,min=10,max=13"`
+	Address        []Address `json:"address,omitempty"`
+	DefaultAddress *Address  `json:"address_default,omitempty"`
+	Password       string    `json:"password,omitempty" validate:"required,min=8"`
+	Token          string    `json:"token,omitempty"`
+	RefreshToken   string    `json:"refresh_token,omitempty"`
+	Role           string    `json:"role,omitempty"`
+	EmailVerified  *bool     `json:"email_verified,omitempty"`
+	PhoneVerified  *bool     `json:"phone_verified,omitempty"`
+	Unverified     bool      `json:"unverified,omitempty"`
+	UpdatedAt      time.Time `json:"-"`
+}
+
+type Session struct {
+	ID               int       `json:"id"`
+	UserID           int       `json:"user_id"`
+	Token            string    `json:"token,omitempty"`
+	RefreshToken     string    `json:"refresh_token,omitempty"`
+	TokenHash        string    `json:"-"`
+	RefreshHash      string    `json:"-"`
+	Role             string    `json:"role,omitempty"`
+	UserAgent        string    `json:"device"`
+	IPAddress        string    `json:"ip_address"`
+	Current          bool      `json:"current"`
+	ExpiresAt        time.Time `json:"expires_at"`
+	RefreshExpiresAt time.Time `json:"-"`
+	LastSeenAt       time.Time `json:"last_seen_at"`
+	CreatedAt        time.Time `json:"created_at"`
+}
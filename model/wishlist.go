@@ -1,7 +1,23 @@
-package model
-
-type Wishlist struct {
-	ID        int `json:"id"`
-	UserID    int `json:"user_id"`
-	ProductID int `json:"product_id"`
-}
+package model
+
+type Wishlist struct {
+	ID        int `json:"id"`
+	UserID    int `json:"user_id"`
+	ProductID int `json:"product_id"`
+}
+
+type WishlistItem struct {
+	WishlistID int `json:"wishlist_id"`
+	Product
+	Stock     int  `json:"stock"`
+	Available bool `json:"available"`
+}
+
+type SharedWishlist struct {
+	OwnerName string          `json:"owner_name"`
+	Items     []*WishlistItem `json:"items"`
+}
+
+type WishlistShare struct {
+	Token string `json:"token"`
+}
@@ -0,0 +1,54 @@
+package notify
+
+import (
+	"context"
+	"encoding/json"
+	"os"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// LogNotifier delivers nothing. Every message is appended as a JSON line to
+// an outbox file and logged, so codes can be read back during development.
+type LogNotifier struct {
+	path string
+	log  *zap.Logger
+	mu   sync.Mutex
+}
+
+func NewLogNotifier(path string, logger *zap.Logger) *LogNotifier {
+	if path == "" {
+		path = "outbox.log"
+	}
+	return &LogNotifier{path: path, log: logger}
+}
+
+func (n *LogNotifier) Send(ctx context.Context, msg Message) error {
+	line, err := json.Marshal(struct {
+		Message
+		SentAt time.Time `json:"sent_at"`
+	}{msg, time.Now()})
+	if err != nil {
+		return err
+	}
+
+	n.mu.Lock()
+	defer n.mu.Unlock()
+
+	file, err := os.OpenFile(n.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0600)
+	if err != nil {
+		n.log.Error("Notifier: failed to open outbox", zap.String("path", n.path), zap.Error(err))
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(append(line, '\n')); err != nil {
+		n.log.Error("Notifier: failed to write outbox", zap.String("path", n.path), zap.Error(err))
+		return err
+	}
+
+	n.log.Info("Notifier: message written to outbox", zap.String("channel", msg.Channel), zap.String("subject", msg.Subject))
+	return nil
+}
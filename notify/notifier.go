@@ -0,0 +1,34 @@
+package notify
+
+import (
+	"context"
+	"ecommerce/util"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+const (
+	ChannelEmail = "email"
+	ChannelSMS   = "sms"
+)
+
+type Message struct {
+	Channel string `json:"channel"`
+	To      string `json:"to"`
+	Subject string `json:"subject,omitempty"`
+	Body    string `json:"body"`
+}
+
+type Notifier interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+func NewNotifier(config util.Configuration, logger *zap.Logger) (Notifier, error) {
+	switch config.Notify.Driver {
+	case "", "log":
+		return NewLogNotifier(config.Notify.OutboxFile, logger), nil
+	default:
+		return nil, fmt.Errorf("unsupported notifier %q", config.Notify.Driver)
+	}
+}
@@ -0,0 +1,75 @@
+package payment
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+)
+
+// FakeProvider accepts every intent without talking to a real gateway.
+// Webhooks are signed with a hex HMAC-SHA256 of the raw body so local
+// callers can simulate gateway notifications with Sign.
+type FakeProvider struct {
+	secret []byte
+}
+
+func NewFakeProvider(webhookSecret string) *FakeProvider {
+	return &FakeProvider{secret: []byte(webhookSecret)}
+}
+
+func (p *FakeProvider) Name() string {
+	return "fake"
+}
+
+func (p *FakeProvider) CreateIntent(ctx context.Context, intent Intent) (*IntentResult, error) {
+	bytes := make([]byte, 12)
+	if _, err := rand.Read(bytes); err != nil {
+		return nil, err
+	}
+	ref := "fake_pi_" + hex.EncodeToString(bytes)
+
+	return &IntentResult{
+		ProviderRef:  ref,
+		ClientSecret: ref + "_secret",
+		RedirectURL:  fmt.Sprintf("/fake-pay/%s?order_id=%d", ref, intent.OrderID),
+	}, nil
+}
+
+func (p *FakeProvider) Capture(ctx context.Context, providerRef string, amount float64) error {
+	return nil
+}
+
+func (p *FakeProvider) Refund(ctx context.Context, providerRef string, amount float64) error {
+	return nil
+}
+
+func (p *FakeProvider) mac(payload []byte) []byte {
+	mac := hmac.New(sha256.New, p.secret)
+	mac.Write(payload)
+	return mac.Sum(nil)
+}
+
+func (p *FakeProvider) Sign(payload []byte) string {
+	return hex.EncodeToString(p.mac(payload))
+}
+
+func (p *FakeProvider) VerifyWebhook(payload []byte, signature string) (*Event, error) {
+	received, err := hex.DecodeString(signature)
+	if err != nil || !hmac.Equal(received, p.mac(payload)) {
+		return nil, ErrInvalidSignature
+	}
+
+	var event Event
+	if err := json.Unmarshal(payload, &event); err != nil {
+		return nil, fmt.Errorf("invalid webhook payload: %w", err)
+	}
+	if event.ID == "" || event.Type == "" || event.ProviderRef == "" {
+		return nil, fmt.Errorf("invalid webhook payload: id, type and provider_ref are required")
+	}
+
+	return &event, nil
+}
@@ -0,0 +1,62 @@
+package payment
+
+import (
+	"context"
+	"ecommerce/util"
+	"errors"
+	"fmt"
+)
+
+var ErrInvalidSignature = errors.New("invalid webhook signature")
+
+const (
+	EventAuthorized = "payment.authorized"
+	EventSucceeded  = "payment.succeeded"
+	EventFailed     = "payment.failed"
+	EventRefunded   = "payment.refunded"
+)
+
+type Intent struct {
+	OrderID  int
+	Amount   float64
+	Currency string
+}
+
+type IntentResult struct {
+	ProviderRef  string
+	ClientSecret string
+	RedirectURL  string
+}
+
+// Event is a webhook notification after its signature has been verified.
+// ID is unique per delivery attempt group, so it is used to drop replays.
+type Event struct {
+	ID          string  `json:"id"`
+	Type        string  `json:"type"`
+	ProviderRef string  `json:"provider_ref"`
+	Amount      float64 `json:"amount"`
+}
+
+type PaymentProvider interface {
+	Name() string
+	CreateIntent(ctx context.Context, intent Intent) (*IntentResult, error)
+	Capture(ctx context.Context, providerRef string, amount float64) error
+	Refund(ctx context.Context, providerRef string, amount float64) error
+	VerifyWebhook(payload []byte, signature string) (*Event, error)
+}
+
+// NewPaymentProvider refuses to start without a webhook secret: an HMAC
+// keyed with an empty secret can be computed by anyone, which would let
+// forged webhooks mark orders as paid.
+func NewPaymentProvider(config util.Configuration) (PaymentProvider, error) {
+	if config.Payment.WebhookSecret == "" {
+		return nil, fmt.Errorf("PAYMENT_WEBHOOK_SECRET must be set")
+	}
+
+	switch config.Payment.Provider {
+	case "", "fake":
+		return NewFakeProvider(config.Payment.WebhookSecret), nil
+	default:
+		return nil, fmt.Errorf("unsupported payment provider %q", config.Payment.Provider)
+	}
+}
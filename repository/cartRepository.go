@@ -0,0 +1,437 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ecommerce/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var ErrInvalidVariant = errors.New("invalid variant")
+
+type CartRepository interface {
+	GetAllCart(userID int) ([]*model.Checkout, error)
+	GetTotalCart(userID int) (*model.Checkout, error)
+	AddCart(cart model.Checkout) error
+	MoveWishlistToCart(wishlistID, userID int, variant map[string]string) (*model.Checkout, error)
+	DeleteCart(id, userID int) error
+	UpdateCart(userID, id, quantity int) (*model.Checkout, error)
+	RepriceCart(userID int, productIDs []int) ([]model.CartNotice, error)
+}
+
+type cartRepository struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewCartRepository(db *sql.DB, logger *zap.Logger) CartRepository {
+	return &cartRepository{db: db, log: logger}
+}
+
+func (r *cartRepository) GetAllCart(userID int) ([]*model.Checkout, error) {
+	query := `
+	SELECT ci.id, ci.product_id, p."name", p.images->>0 as image, ci.variant, ci.price, ci.quantity, ci.quantity * ci.price AS total
+	FROM cart_items ci
+	JOIN carts c ON c.id = ci.cart_id
+	JOIN products p ON p.id = ci.product_id
+	WHERE c.user_id = $1
+	ORDER BY ci.id ASC
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.Checkout
+	for rows.Next() {
+		var result model.Checkout
+		var variantJSON []byte
+		if err := rows.Scan(&result.ID, &result.ProductID, &result.Name, &result.Image, &variantJSON, &result.Price, &result.Quantity, &result.TotalPrice); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		if err := json.Unmarshal(variantJSON, &result.Variant); err != nil {
+			r.log.Error("Repository: failed to unmarshal variant JSON", zap.Error(err))
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+
+	return results, nil
+}
+
+func (r *cartRepository) GetTotalCart(userID int) (*model.Checkout, error) {
+	query := `
+	SELECT COUNT(*) FROM cart_items ci
+	JOIN carts c ON c.id = ci.cart_id
+	WHERE c.user_id = $1
+	`
+	var result model.Checkout
+	err := r.db.QueryRow(query, userID).Scan(&result.TotalCarts)
+	if err != nil {
+		r.log.Error("Repository: failed to count total items in cart", zap.Error(err))
+		return nil, err
+	}
+
+	return &result, nil
+}
+
+func (r *cartRepository) RepriceCart(userID int, productIDs []int) ([]model.CartNotice, error) {
+	return saveRepricedCart(context.Background(), r.db, r.log, userID, productIDs)
+}
+
+// saveRepricedCart runs repriceCart in a transaction of its own and keeps
+// the new prices.
+func saveRepricedCart(ctx context.Context, db *sql.DB, log *zap.Logger, userID int, productIDs []int) ([]model.CartNotice, error) {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	notices, err := repriceCart(ctx, tx, log, userID, productIDs)
+	if err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	if len(notices) > 0 {
+		log.Info("Repository: cart repriced", zap.Int("userID", userID), zap.Int("changes", len(notices)))
+	}
+	return notices, nil
+}
+
+// repriceCart brings the user's cart lines for productIDs (every line when
+// empty) to their current price inside tx and reports each line that
+// changed. The lines stay locked until tx ends, so the prices cannot move
+// again before the caller is done with them. Lines that can no longer be
+// bought keep their old price and are reported as unavailable.
+func repriceCart(ctx context.Context, tx *sql.Tx, log *zap.Logger, userID int, productIDs []int) ([]model.CartNotice, error) {
+	query := `
+	SELECT ci.id, ci.product_id, p.name, ci.price, ci.promotion_percentage, pr.unit_price, pr.promotion_percentage,
+		p.deleted_at IS NULL AND ci.quantity <= COALESCE((SELECT i.quantity - i.reserved FROM inventories i WHERE i.product_id = ci.product_id AND i.variant IN (ci.variant, '{}'::jsonb) ORDER BY i.variant = '{}'::jsonb LIMIT 1), 0) AS available
+	FROM cart_items ci
+	JOIN carts c ON c.id = ci.cart_id
+	JOIN products p ON p.id = ci.product_id
+	CROSS JOIN LATERAL product_price(ci.product_id, ci.quantity) pr
+	WHERE c.user_id = $1 AND (COALESCE(cardinality($2::int[]), 0) = 0 OR ci.product_id = ANY($2))
+	ORDER BY ci.id ASC
+	FOR UPDATE OF ci
+	`
+	rows, err := tx.QueryContext(ctx, query, userID, pq.Array(productIDs))
+	if err != nil {
+		log.Error("Repository: failed to query cart prices", zap.Error(err))
+		return nil, err
+	}
+
+	type repricedLine struct {
+		id        int
+		price     float64
+		promotion int
+	}
+	var notices []model.CartNotice
+	var changed []repricedLine
+	for rows.Next() {
+		var notice model.CartNotice
+		var oldPromotion, newPromotion int
+		var available bool
+		if err := rows.Scan(&notice.CartItemID, &notice.ProductID, &notice.Name, &notice.OldPrice, &oldPromotion, &notice.NewPrice, &newPromotion, &available); err != nil {
+			rows.Close()
+			log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+
+		if !available {
+			notice.Type = model.CartNoticeUnavailable
+			notice.NewPrice = notice.OldPrice
+			notices = append(notices, notice)
+			continue
+		}
+		if notice.NewPrice != notice.OldPrice || newPromotion != oldPromotion {
+			changed = append(changed, repricedLine{notice.CartItemID, notice.NewPrice, newPromotion})
+		}
+
+		switch {
+		case notice.NewPrice == notice.OldPrice:
+			continue
+		case notice.NewPrice > notice.OldPrice && oldPromotion > 0 && newPromotion == 0:
+			notice.Type = model.CartNoticePromotionEnded
+		case notice.NewPrice > notice.OldPrice:
+			notice.Type = model.CartNoticePriceIncreased
+		default:
+			notice.Type = model.CartNoticePriceDecreased
+		}
+		notices = append(notices, notice)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	for _, line := range changed {
+		_, err := tx.ExecContext(ctx, `UPDATE cart_items SET price = $2, promotion_percentage = $3, updated_at = NOW() WHERE id = $1`, line.id, line.price, line.promotion)
+		if err != nil {
+			log.Error("Repository: failed to reprice cart item", zap.Error(err))
+			return nil, fmt.Errorf("failed to reprice cart item: %w", err)
+		}
+	}
+
+	return notices, nil
+}
+
+type rowQuerier interface {
+	QueryRow(query string, args ...interface{}) *sql.Row
+}
+
+func (r *cartRepository) AddCart(cart model.Checkout) error {
+	return r.addCart(r.db, &cart)
+}
+
+func (r *cartRepository) MoveWishlistToCart(wishlistID, userID int, variant map[string]string) (*model.Checkout, error) {
+	tx, err := r.db.Begin()
+	if err != nil {
+		return nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	cart := model.Checkout{UserID: userID, Variant: variant}
+	err = tx.QueryRow(`DELETE FROM wishlists WHERE id = $1 AND user_id = $2 RETURNING product_id`, wishlistID, userID).Scan(&cart.ProductID)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: No wishlist found for the given userID and id", zap.Int("id", wishlistID), zap.Int("userID", userID))
+			return nil, fmt.Errorf("no wishlist found")
+		}
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return nil, err
+	}
+
+	if err := r.addCart(tx, &cart); err != nil {
+		tx.Rollback()
+		return nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.log.Info("Repository: wishlist moved to cart", zap.Int("wishlistID", wishlistID), zap.Int("cartID", cart.ID))
+	return &cart, nil
+}
+
+func (r *cartRepository) addCart(q rowQuerier, cart *model.Checkout) error {
+	var schemaJSON []byte
+	schemaQuery := `
+	SELECT COALESCE(c.variant, '{}'::jsonb)
+	FROM products p
+	LEFT JOIN categories c ON c.id = p.category_id
+	WHERE p.id = $1 AND p.deleted_at IS NULL
+	`
+	err := q.QueryRow(schemaQuery, cart.ProductID).Scan(&schemaJSON)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: product not found", zap.Int("productID", cart.ProductID))
+			return ErrProductNotFound
+		}
+		r.log.Error("Repository: failed to fetch variant schema", zap.Error(err))
+		return err
+	}
+
+	var schema map[string][]string
+	if err := json.Unmarshal(schemaJSON, &schema); err != nil {
+		r.log.Error("Repository: failed to unmarshal variant JSON", zap.Error(err))
+		return err
+	}
+	if err := validateVariant(schema, cart.Variant); err != nil {
+		r.log.Warn("Repository: invalid variant", zap.Int("productID", cart.ProductID), zap.Error(err))
+		return err
+	}
+	if cart.Variant == nil {
+		cart.Variant = map[string]string{}
+	}
+	variantJSON, err := json.Marshal(cart.Variant)
+	if err != nil {
+		return fmt.Errorf("failed to serialize variant: %w", err)
+	}
+
+	var cartID int
+	cartQuery := `
+	INSERT INTO carts (user_id) VALUES ($1)
+	ON CONFLICT (user_id) DO UPDATE SET updated_at = NOW()
+	RETURNING id
+	`
+	if err := q.QueryRow(cartQuery, cart.UserID).Scan(&cartID); err != nil {
+		r.log.Error("Repository: failed to get cart", zap.Error(err))
+		return err
+	}
+
+	var available, inCart int
+	stockQuery := `
+	SELECT 
+		COALESCE((SELECT quantity - reserved FROM inventories WHERE product_id = $2 AND variant IN ($3::jsonb, '{}'::jsonb) ORDER BY variant = '{}'::jsonb LIMIT 1), 0),
+		COALESCE((SELECT quantity FROM cart_items WHERE cart_id = $1 AND product_id = $2 AND variant = $3::jsonb), 0)
+	`
+	err = q.QueryRow(stockQuery, cartID, cart.ProductID, variantJSON).Scan(&available, &inCart)
+	if err != nil {
+		r.log.Error("Repository: failed to check stock", zap.Error(err))
+		return err
+	}
+	if inCart+1 > available {
+		r.log.Warn("Repository: insufficient stock", zap.Int("productID", cart.ProductID), zap.Int("available", available))
+		return fmt.Errorf("%w for product %d", ErrInsufficientStock, cart.ProductID)
+	}
+
+	// The line is repriced for its new quantity so bulk tiers kick in as it grows.
+	query := `
+	INSERT INTO cart_items (cart_id, product_id, variant, quantity, price, promotion_percentage)
+	SELECT $1, $2, $3, 1, pr.unit_price, pr.promotion_percentage FROM product_price($2, 1) pr
+	ON CONFLICT (cart_id, product_id, variant)
+	DO UPDATE SET 
+	quantity = cart_items.quantity + 1,
+	price = (SELECT unit_price FROM product_price(cart_items.product_id, cart_items.quantity + 1)),
+	promotion_percentage = EXCLUDED.promotion_percentage,
+	updated_at = NOW()
+	RETURNING id
+	`
+	err = q.QueryRow(query, cartID, cart.ProductID, variantJSON).Scan(&cart.ID)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	r.log.Info("Repository: cart added successfully", zap.Int("id", cart.ID))
+	return nil
+}
+
+// validateVariant requires one allowed value for every option in the
+// category's variant schema and nothing else.
+func validateVariant(schema map[string][]string, selected map[string]string) error {
+	for option := range selected {
+		if _, ok := schema[option]; !ok {
+			return fmt.Errorf("%w: unknown option %q", ErrInvalidVariant, option)
+		}
+	}
+	for option, values := range schema {
+		if len(values) == 0 {
+			continue
+		}
+		value, ok := selected[option]
+		if !ok {
+			return fmt.Errorf("%w: %s is required", ErrInvalidVariant, option)
+		}
+		allowed := false
+		for _, v := range values {
+			if v == value {
+				allowed = true
+				break
+			}
+		}
+		if !allowed {
+			return fmt.Errorf("%w: %s must be one of %v", ErrInvalidVariant, option, values)
+		}
+	}
+	return nil
+}
+
+func (r *cartRepository) DeleteCart(id, userID int) error {
+	query := `DELETE FROM cart_items ci USING carts c WHERE ci.cart_id = c.id AND ci.id = $1 AND c.user_id = $2`
+	res, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.log.Warn("Repository: No cart found for the given userID and id")
+		return fmt.Errorf("no cart found")
+	}
+
+	r.log.Info("Repository: Cart deleted successfully", zap.Int("id", id), zap.Int("userID", userID))
+	return nil
+}
+
+func (r *cartRepository) UpdateCart(userID, id, quantity int) (*model.Checkout, error) {
+	if quantity == 0 {
+
+		query := `
+			DELETE FROM cart_items ci
+			USING carts c
+			WHERE ci.cart_id = c.id AND ci.id = $1 AND c.user_id = $2
+			RETURNING ci.id;
+		`
+
+		var deletedID int
+		err := r.db.QueryRow(query, id, userID).Scan(&deletedID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				r.log.Warn("Repository: No cart found for the given userID and id", zap.Int("userID", userID), zap.Int("id", id), zap.Int("qty", quantity))
+				return nil, fmt.Errorf("cart not found")
+			}
+			r.log.Error("Repository: Error deleting cart item", zap.Error(err))
+			return nil, err
+		}
+
+		r.log.Info("Repository: cart item deleted successfully", zap.Int("id", deletedID))
+		return nil, nil
+	}
+
+	var productID, available int
+	stockQuery := `
+	SELECT ci.product_id,
+		COALESCE((SELECT i.quantity - i.reserved FROM inventories i WHERE i.product_id = ci.product_id AND i.variant IN (ci.variant, '{}'::jsonb) ORDER BY i.variant = '{}'::jsonb LIMIT 1), 0)
+	FROM cart_items ci
+	JOIN carts c ON c.id = ci.cart_id
+	WHERE ci.id = $1 AND c.user_id = $2
+	`
+	if err := r.db.QueryRow(stockQuery, id, userID).Scan(&productID, &available); err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: No cart found for the given userID and id", zap.Int("userID", userID), zap.Int("id", id), zap.Int("qty", quantity))
+			return nil, fmt.Errorf("cart not found")
+		}
+		r.log.Error("Repository: failed to check stock", zap.Error(err))
+		return nil, err
+	}
+	if quantity > available {
+		r.log.Warn("Repository: insufficient stock", zap.Int("productID", productID), zap.Int("available", available))
+		return nil, fmt.Errorf("%w for product %d", ErrInsufficientStock, productID)
+	}
+
+	query := `
+		UPDATE cart_items ci
+		SET 
+			quantity = $3::INTEGER,
+			price = (SELECT unit_price FROM product_price(ci.product_id, $3::INTEGER)),
+			promotion_percentage = (SELECT promotion_percentage FROM product_price(ci.product_id, $3::INTEGER)),
+			updated_at = NOW()
+		FROM carts c
+		WHERE ci.cart_id = c.id AND ci.id = $1 AND c.user_id = $2
+		RETURNING ci.id, ci.product_id, ci.variant, ci.quantity, ci.quantity * ci.price;
+	`
+
+	var result model.Checkout
+	var variantJSON []byte
+	err := r.db.QueryRow(query, id, userID, quantity).Scan(&result.ID, &result.ProductID, &variantJSON, &result.Quantity, &result.TotalPrice)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: No cart found for the given userID and id", zap.Int("userID", userID), zap.Int("id", id), zap.Int("qty", quantity))
+			return nil, fmt.Errorf("cart not found")
+		}
+		r.log.Error("Repository: Error updating cart quantity", zap.Error(err))
+		return nil, err
+	}
+	if err := json.Unmarshal(variantJSON, &result.Variant); err != nil {
+		r.log.Error("Repository: failed to unmarshal variant JSON", zap.Error(err))
+		return nil, err
+	}
+
+	r.log.Info("Repository: cart quantity updated successfully", zap.Int("id", result.ID))
+	return &result, nil
+}
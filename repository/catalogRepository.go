@@ -0,0 +1,298 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ecommerce/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var ErrCategoryInUse = errors.New("category still has active products")
+
+type CatalogRepository interface {
+	GetProduct(id int) (*model.CatalogProduct, error)
+	CreateProduct(product *model.CatalogProduct) error
+	UpdateProduct(product *model.CatalogProduct) error
+	DeleteProduct(id int) error
+	CreateCategory(category *model.Category) error
+	UpdateCategory(category *model.Category) error
+	DeleteCategory(id int) error
+}
+
+type catalogRepository struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewCatalogRepository(db *sql.DB, logger *zap.Logger) CatalogRepository {
+	return &catalogRepository{db: db, log: logger}
+}
+
+func (r *catalogRepository) GetProduct(id int) (*model.CatalogProduct, error) {
+	query := `
+	SELECT p.id, p.category_id, p.name, p.title, p.subtitle, p.images, COALESCE(p.description, ''), p.price, p.weight,
+	COALESCE((SELECT SUM(i.quantity) FROM inventories i WHERE i.product_id = p.id), 0) AS stock,
+	p.created_at, p.updated_at, p.deleted_at
+	FROM products p
+	WHERE p.id = $1
+	`
+	var product model.CatalogProduct
+	var imagesJSON []byte
+	var stock int
+	err := r.db.QueryRow(query, id).Scan(&product.ID, &product.CategoryID, &product.Name, &product.Title, &product.Subtitle, &imagesJSON,
+		&product.Description, &product.Price, &product.Weight, &stock, &product.CreatedAt, &product.UpdatedAt, &product.DeletedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: Product not found", zap.Int("id", id))
+			return nil, fmt.Errorf("product not found")
+		}
+		r.log.Error("Repository: failed to query product", zap.Error(err))
+		return nil, err
+	}
+
+	if err := json.Unmarshal(imagesJSON, &product.Images); err != nil {
+		r.log.Error("Repository: failed to unmarshal images JSON", zap.Error(err))
+		return nil, err
+	}
+	product.Stock = &stock
+
+	tiers, err := getPriceTiers(context.Background(), r.db, id)
+	if err != nil {
+		r.log.Error("Repository: failed to query price tiers", zap.Error(err))
+		return nil, err
+	}
+	product.PriceTiers = tiers
+
+	return &product, nil
+}
+
+func (r *catalogRepository) CreateProduct(product *model.CatalogProduct) error {
+	imagesJSON, err := json.Marshal(product.Images)
+	if err != nil {
+		return fmt.Errorf("failed to serialize images: %w", err)
+	}
+
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	query := `
+	INSERT INTO products (category_id, name, title, subtitle, images, description, price, weight)
+	SELECT c.id, $2, $3, $4, $5, NULLIF($6, ''), $7, COALESCE(NULLIF($8, 0), 1000)
+	FROM categories c
+	WHERE c.id = $1 AND c.deleted_at IS NULL
+	RETURNING id, weight, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query, product.CategoryID, product.Name, product.Title, product.Subtitle, imagesJSON, product.Description, product.Price, product.Weight).
+		Scan(&product.ID, &product.Weight, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: Category not found", zap.Int("categoryID", product.CategoryID))
+			return fmt.Errorf("category not found")
+		}
+		r.log.Error("Repository: failed to create product", zap.Error(err))
+		return fmt.Errorf("failed to create product: %w", err)
+	}
+
+	stock := 0
+	if product.Stock != nil {
+		stock = *product.Stock
+	}
+	_, err = tx.ExecContext(ctx, `INSERT INTO inventories (product_id, quantity) VALUES ($1, $2)`, product.ID, stock)
+	if err != nil {
+		tx.Rollback()
+		r.log.Error("Repository: failed to create inventory", zap.Error(err))
+		return fmt.Errorf("failed to create inventory: %w", err)
+	}
+	product.Stock = &stock
+
+	if err := replacePriceTiers(ctx, tx, product.ID, product.PriceTiers); err != nil {
+		tx.Rollback()
+		r.log.Error("Repository: failed to save price tiers", zap.Error(err))
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.log.Info("Repository: Product created successfully", zap.Int("id", product.ID))
+	return nil
+}
+
+func (r *catalogRepository) UpdateProduct(product *model.CatalogProduct) error {
+	imagesJSON, err := json.Marshal(product.Images)
+	if err != nil {
+		return fmt.Errorf("failed to serialize images: %w", err)
+	}
+
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	query := `
+	UPDATE products
+	SET category_id = $2, name = $3, title = $4, subtitle = $5, images = $6, description = NULLIF($7, ''), price = $8,
+		weight = COALESCE(NULLIF($9, 0), weight), updated_at = NOW()
+	WHERE id = $1 AND deleted_at IS NULL
+	  AND EXISTS (SELECT 1 FROM categories c WHERE c.id = $2 AND c.deleted_at IS NULL)
+	RETURNING weight, created_at, updated_at
+	`
+	err = tx.QueryRowContext(ctx, query, product.ID, product.CategoryID, product.Name, product.Title, product.Subtitle, imagesJSON, product.Description, product.Price, product.Weight).
+		Scan(&product.Weight, &product.CreatedAt, &product.UpdatedAt)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: Product or category not found", zap.Int("id", product.ID), zap.Int("categoryID", product.CategoryID))
+			return fmt.Errorf("product or category not found")
+		}
+		r.log.Error("Repository: failed to update product", zap.Error(err))
+		return fmt.Errorf("failed to update product: %w", err)
+	}
+
+	if product.Stock != nil {
+		stockQuery := `
+		INSERT INTO inventories (product_id, quantity) VALUES ($1, $2)
+		ON CONFLICT (product_id, variant) DO UPDATE SET quantity = EXCLUDED.quantity, updated_at = NOW()
+		`
+		if _, err := tx.ExecContext(ctx, stockQuery, product.ID, *product.Stock); err != nil {
+			tx.Rollback()
+			var pqErr *pq.Error
+			if errors.As(err, &pqErr) && pqErr.Constraint == "inventories_reserved_check" {
+				return fmt.Errorf("%w: stock cannot be lower than reserved quantity", ErrInsufficientStock)
+			}
+			r.log.Error("Repository: failed to update inventory", zap.Error(err))
+			return fmt.Errorf("failed to update inventory: %w", err)
+		}
+	}
+
+	// Tiers are left alone when omitted; an empty list removes them.
+	if product.PriceTiers != nil {
+		if err := replacePriceTiers(ctx, tx, product.ID, product.PriceTiers); err != nil {
+			tx.Rollback()
+			r.log.Error("Repository: failed to save price tiers", zap.Error(err))
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.log.Info("Repository: Product updated successfully", zap.Int("id", product.ID))
+	return nil
+}
+
+func (r *catalogRepository) DeleteProduct(id int) error {
+	query := `UPDATE products SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`
+	res, err := r.db.Exec(query, id)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.log.Warn("Repository: No product found for the given id", zap.Int("id", id))
+		return fmt.Errorf("product not found")
+	}
+
+	r.log.Info("Repository: Product deleted successfully", zap.Int("id", id))
+	return nil
+}
+
+func (r *catalogRepository) CreateCategory(category *model.Category) error {
+	variant := category.Variant
+	if variant == nil {
+		variant = map[string][]string{}
+	}
+	variantJSON, err := json.Marshal(variant)
+	if err != nil {
+		return fmt.Errorf("failed to serialize variant: %w", err)
+	}
+
+	query := `INSERT INTO categories (name, variant) VALUES ($1, $2) RETURNING id`
+	err = r.db.QueryRow(query, category.Name, variantJSON).Scan(&category.ID)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			r.log.Warn("Repository: category already exists", zap.String("name", category.Name))
+			return fmt.Errorf("category already exists")
+		}
+		r.log.Error("Repository: failed to create category", zap.Error(err))
+		return fmt.Errorf("failed to create category: %w", err)
+	}
+
+	r.log.Info("Repository: Category created successfully", zap.Int("id", category.ID))
+	return nil
+}
+
+func (r *catalogRepository) UpdateCategory(category *model.Category) error {
+	variant := category.Variant
+	if variant == nil {
+		variant = map[string][]string{}
+	}
+	variantJSON, err := json.Marshal(variant)
+	if err != nil {
+		return fmt.Errorf("failed to serialize variant: %w", err)
+	}
+
+	query := `UPDATE categories SET name = $2, variant = $3 WHERE id = $1 AND deleted_at IS NULL`
+	res, err := r.db.Exec(query, category.ID, category.Name, variantJSON)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			r.log.Warn("Repository: category already exists", zap.String("name", category.Name))
+			return fmt.Errorf("category already exists")
+		}
+		r.log.Error("Repository: failed to update category", zap.Error(err))
+		return fmt.Errorf("failed to update category: %w", err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.log.Warn("Repository: No category found for the given id", zap.Int("id", category.ID))
+		return fmt.Errorf("category not found")
+	}
+
+	r.log.Info("Repository: Category updated successfully", zap.Int("id", category.ID))
+	return nil
+}
+
+func (r *catalogRepository) DeleteCategory(id int) error {
+	var inUse bool
+	err := r.db.QueryRow(`SELECT EXISTS (SELECT 1 FROM products WHERE category_id = $1 AND deleted_at IS NULL)`, id).Scan(&inUse)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+	if inUse {
+		r.log.Warn("Repository: Category still in use", zap.Int("id", id))
+		return ErrCategoryInUse
+	}
+
+	res, err := r.db.Exec(`UPDATE categories SET deleted_at = NOW() WHERE id = $1 AND deleted_at IS NULL`, id)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.log.Warn("Repository: No category found for the given id", zap.Int("id", id))
+		return fmt.Errorf("category not found")
+	}
+
+	r.log.Info("Repository: Category deleted successfully", zap.Int("id", id))
+	return nil
+}
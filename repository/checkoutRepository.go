@@ -1,296 +1,691 @@
-package repository
-
-import (
-	"context"
-	"database/sql"
-	"ecommerce/model"
-	"errors"
-	"fmt"
-	"strconv"
-
-	"github.com/lib/pq"
-	"go.uber.org/zap"
-)
-
-type CheckoutRepository interface {
-	GetAllCart(userID int) ([]*model.Checkout, error)
-	GetTotalCart(userID int) (*model.Checkout, error)
-	AddCart(cart model.Checkout) error
-	DeleteCart(id, userID int) error
-	UpdateCart(userID, productID, quantity int) (*model.Checkout, error)
-	CreateOrder(userID int, productID []int, addressIndex int) (*model.OrderResponse, error)
-}
-
-type checkoutRepository struct {
-	db  *sql.DB
-	log *zap.Logger
-}
-
-func NewCheckoutRepository(db *sql.DB, logger *zap.Logger) CheckoutRepository {
-	return &checkoutRepository{db: db, log: logger}
-}
-
-func (r *checkoutRepository) GetAllCart(userID int) ([]*model.Checkout, error) {
-	query := `
-	SELECT oi.id, p."name", p.images->>0 as image, oi.price, oi.quantity, oi.total 
-	FROM products p 
-	JOIN order_items oi ON p.id = oi.product_id 
-	WHERE oi.user_id = $1 AND order_id IS NULL
-	`
-	rows, err := r.db.Query(query, userID)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []*model.Checkout
-	for rows.Next() {
-		var result model.Checkout
-		if err := rows.Scan(&result.ID, &result.Name, &result.Image, &result.Price, &result.Quantity, &result.TotalPrice); err != nil {
-			r.log.Error("Repository: failed to scan row", zap.Error(err))
-			return nil, err
-		}
-		results = append(results, &result)
-	}
-
-	return results, nil
-}
-func (r *checkoutRepository) GetTotalCart(userID int) (*model.Checkout, error) {
-	query := `
-	SELECT COUNT(*) FROM order_items
-	WHERE user_id = $1 AND order_id IS NULL
-	`
-	var result model.Checkout
-	err := r.db.QueryRow(query, userID).Scan(&result.TotalCarts)
-	if err != nil {
-		r.log.Error("Repository: failed to count total items in cart", zap.Error(err))
-		return nil, err
-	}
-
-	return &result, nil
-}
-
-func (r *checkoutRepository) AddCart(cart model.Checkout) error {
-	query := `
-	WITH DiscountedPrice AS (SELECT p.id AS product_id, p.price, p.price * (1 - CAST(COALESCE(CASE 
-    WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-    ELSE 0 END, 0) AS FLOAT) / 100) AS discount_price
-    FROM products p
-    LEFT JOIN weekly_promotions wp ON p.id = wp.product_id
-    WHERE p.id = $2
-    GROUP BY p.id, p.price, wp.discount_percentage)
-	INSERT INTO order_items (user_id, product_id, quantity, price, total)
-	VALUES ($1, $2, 1, (SELECT discount_price FROM DiscountedPrice), (SELECT discount_price FROM DiscountedPrice))
-	ON CONFLICT (user_id, product_id) 
-	DO UPDATE SET 
-	quantity = order_items.quantity + 1,
-	total = (order_items.quantity + 1) * order_items.price
-	RETURNING id
-	`
-	err := r.db.QueryRow(query, cart.UserID, cart.ProductID).Scan(&cart.ID)
-	if err != nil {
-		r.log.Error("Repository: Error executing query", zap.Error(err))
-		return err
-	}
-
-	r.log.Info("Repository: cart added successfully", zap.Int("id", cart.ID))
-	return nil
-}
-
-func (r *checkoutRepository) DeleteCart(id, userID int) error {
-	query := `DELETE FROM order_items WHERE id = $1 AND user_id = $2 AND order_id IS NULL`
-	res, err := r.db.Exec(query, id, userID)
-	if err != nil {
-		r.log.Error("Repository: Error executing query", zap.Error(err))
-		return err
-	}
-
-	rowsAffected, _ := res.RowsAffected()
-	if rowsAffected == 0 {
-		r.log.Warn("Repository: No cart found for the given userID and id")
-		return fmt.Errorf("no cart found")
-	}
-
-	r.log.Info("Repository: Cart deleted successfully", zap.Int("id", id), zap.Int("userID", userID))
-	return nil
-}
-
-func (r *checkoutRepository) UpdateCart(userID, productID, quantity int) (*model.Checkout, error) {
-	if quantity == 0 {
-
-		query := `
-			DELETE FROM order_items
-			WHERE user_id = $1 AND product_id = $2 AND order_id IS NULL
-			RETURNING id;
-		`
-
-		var deletedID int
-		err := r.db.QueryRow(query, userID, productID).Scan(&deletedID)
-		if err != nil {
-			if errors.Is(err, sql.ErrNoRows) {
-				r.log.Warn("Repository: No cart found for the given userID and productID", zap.Int("userID", userID), zap.Int("productID", productID), zap.Int("qty", quantity))
-				return nil, fmt.Errorf("cart not found")
-			}
-			r.log.Error("Repository: Error deleting cart item", zap.Error(err))
-			return nil, err
-		}
-
-		r.log.Info("Repository: cart item deleted successfully", zap.Int("id", deletedID))
-		return nil, nil
-	}
-
-	query := `
-		UPDATE order_items
-		SET 
-			quantity = $3::INTEGER,
-			total = $3::INTEGER * price
-		WHERE user_id = $1 AND product_id = $2 AND order_id IS NULL
-		RETURNING id, quantity, total;
-	`
-
-	var result model.Checkout
-	err := r.db.QueryRow(query, userID, productID, quantity).Scan(&result.ID, &result.Quantity, &result.TotalPrice)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			r.log.Warn("Repository: No cart found for the given userID and productID", zap.Int("userID", userID), zap.Int("productID", productID), zap.Int("qty", quantity))
-			return nil, fmt.Errorf("cart not found")
-		}
-		r.log.Error("Repository: Error updating cart quantity", zap.Error(err))
-		return nil, err
-	}
-
-	r.log.Info("Repository: cart quantity updated successfully", zap.Int("id", result.ID))
-	return &result, nil
-}
-
-func (r *checkoutRepository) CreateOrder(userID int, productID []int, addressIndex int) (*model.OrderResponse, error) {
-	ctx := context.Background()
-	tx, err := r.db.BeginTx(ctx, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to start transaction: %w", err)
-	}
-
-	var shippingAddress *string
-	indexStr := strconv.Itoa(addressIndex)
-
-	addressQuery := `SELECT COALESCE(address->>$2, address->>0) FROM users WHERE id = $1`
-	err = tx.QueryRowContext(ctx, addressQuery, userID, indexStr).Scan(&shippingAddress)
-	if err != nil {
-		tx.Rollback()
-        r.log.Error("Repository: failed to fetch address", zap.Error(err))
-		return nil, fmt.Errorf("failed to fetch address at index %d: %w", addressIndex, err)
-
-	}
-
-	r.log.Info("Repository: Fetching shipping address", zap.Int("user_id", userID), zap.Int("address_index", addressIndex), zap.String("address shipping", *shippingAddress))
-
-	var orderID int
-	insertOrderQuery := `
-        WITH selected_items AS (
-            SELECT 
-                oi.user_id,
-                SUM(oi.quantity * oi.price) AS total_price
-            FROM 
-                order_items oi
-            WHERE 
-                oi.user_id = $1
-                AND oi.product_id = ANY($2)
-                AND oi.order_id IS NULL
-            GROUP BY oi.user_id
-        )
-        INSERT INTO orders (user_id, total_amount, shipping_address)
-        SELECT si.user_id, si.total_price, COALESCE(address->>$3, address->>0) AS shipping_address
-        FROM selected_items si
-        JOIN users u ON si.user_id = u.id
-        RETURNING id, shipping_address;
-    `
-
-	err = tx.QueryRowContext(ctx, insertOrderQuery, userID, pq.Array(productID), indexStr).Scan(&orderID, &shippingAddress)
-	r.log.Info("Repository: Executing query insert order", zap.Int("user_id", userID), zap.Int("address_index", addressIndex), zap.String("address shipping", *shippingAddress))
-
-	if err != nil {
-		tx.Rollback()
-		r.log.Error("Repository: failed to create order", zap.Error(err))
-		return nil, fmt.Errorf("failed to create order: %w", err)
-	}
-
-	updateOrderItemsQuery := `
-        UPDATE order_items
-        SET order_id = $1
-        WHERE user_id = $2 
-          AND product_id = ANY($3) 
-          AND order_id IS NULL;
-    `
-	_, err = tx.ExecContext(ctx, updateOrderItemsQuery, orderID, userID, pq.Array(productID))
-	if err != nil {
-		tx.Rollback()
-		r.log.Error("Repository: failed to update order items", zap.Error(err))
-		return nil, fmt.Errorf("failed to update order items: %w", err)
-	}
-
-	selectOrderDetailsQuery := `
-        WITH selected_items AS (
-            SELECT 
-                p.name AS product_name,
-                p.images->>0 AS image,
-                oi.quantity,
-                (oi.quantity * oi.price) AS subtotal_price
-            FROM 
-                order_items oi
-            JOIN 
-                products p ON oi.product_id = p.id
-            WHERE 
-                oi.user_id = $1
-                AND oi.product_id = ANY($2)
-                AND oi.order_id = $3
-        )
-        SELECT 
-            si.product_name,
-            si.image,
-            si.subtotal_price,
-            o.shipping
-        FROM 
-            selected_items si
-        JOIN 
-            orders o ON o.id = $3;
-    `
-	rows, err := tx.QueryContext(ctx, selectOrderDetailsQuery, userID, pq.Array(productID), orderID)
-	r.log.Info("Repository: Executing query details", zap.Int("user_id", userID), zap.Int("address_index", addressIndex), zap.String("address shipping", *shippingAddress))
-	r.log.Error("Repository: Executing query details", zap.Error(err))
-
-	if err != nil {
-		tx.Rollback()
-		r.log.Error("Repository: failed to retrieve order details", zap.Error(err))
-		return nil, fmt.Errorf("failed to retrieve order details: %w", err)
-	}
-	defer rows.Close()
-
-	var items []model.OrderItem
-	var shipping string
-	var totalAmount float64
-
-	for rows.Next() {
-		var item model.OrderItem
-		if err := rows.Scan(&item.ProductName, &item.Image, &item.SubtotalPrice, &shipping); err != nil {
-			tx.Rollback()
-			r.log.Error("Repository: failed to scan order item", zap.Error(err))
-			return nil, fmt.Errorf("failed to scan order item: %w", err)
-		}
-		totalAmount += item.SubtotalPrice
-		items = append(items, item)
-	}
-
-	if err := tx.Commit(); err != nil {
-		return nil, fmt.Errorf("failed to commit transaction: %w", err)
-	}
-
-	response := &model.OrderResponse{
-		OrderID:         orderID,
-		Items:           items,
-		Shipping:        shipping,
-		TotalAmount:     totalAmount,
-		ShippingAddress: shippingAddress,
-	}
-
-	return response, nil
-}
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ecommerce/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrInsufficientStock  = errors.New("insufficient stock")
+	ErrOrderStatusChanged = errors.New("order status has changed")
+	ErrProductNotFound    = errors.New("product not found")
+	ErrProductNotInCart   = errors.New("product not in cart")
+)
+
+type CheckoutRepository interface {
+	GetCheckoutSummary(userID int, productID []int, addressIndex int) (*model.CheckoutSummary, error)
+	CreateOrder(order model.CreateOrderRequest, shipping *model.ShippingQuote) (*model.OrderResponse, []model.CartNotice, error)
+	GetAllOrders(userID int, status string, startDate, endDate time.Time, page model.Pagination) ([]*model.OrderResponse, model.Pagination, error)
+	GetOrderByID(id, userID int) (*model.OrderResponse, error)
+	GetOrderStatus(id int) (string, error)
+	UpdateOrderStatus(id int, fromStatus, toStatus string, changedBy int, note string) error
+	// updateOrderStatus lets the payment repository move the order inside
+	// its own transaction.
+	updateOrderStatus(ctx context.Context, tx *sql.Tx, id int, fromStatus, toStatus string, changedBy int, note string) error
+}
+
+type checkoutRepository struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewCheckoutRepository(db *sql.DB, logger *zap.Logger) CheckoutRepository {
+	return &checkoutRepository{db: db, log: logger}
+}
+
+func (r *checkoutRepository) GetCheckoutSummary(userID int, productID []int, addressID int) (*model.CheckoutSummary, error) {
+	ctx := context.Background()
+	address, err := findAddress(ctx, r.db, userID, addressID)
+	if err != nil {
+		r.log.Error("Repository: failed to fetch address", zap.Error(err))
+		return nil, err
+	}
+	if address == nil && addressID != 0 {
+		r.log.Warn("Repository: Address not found", zap.Int("user_id", userID), zap.Int("address_id", addressID))
+		return nil, fmt.Errorf("address not found")
+	}
+
+	query := `
+	SELECT COALESCE(SUM(ci.quantity * ci.price), 0),
+		COALESCE(SUM(ci.quantity * p.weight), 0),
+		COUNT(DISTINCT ci.product_id)
+	FROM carts c
+	JOIN cart_items ci ON ci.cart_id = c.id AND ci.product_id = ANY($2)
+	JOIN products p ON p.id = ci.product_id
+	WHERE c.user_id = $1
+	`
+	var summary model.CheckoutSummary
+	var found int
+	err = r.db.QueryRowContext(ctx, query, userID, pq.Array(productID)).Scan(&summary.Subtotal, &summary.WeightGrams, &found)
+	if err != nil {
+		r.log.Error("Repository: failed to fetch checkout summary", zap.Error(err))
+		return nil, err
+	}
+	// Quoting products that are not in the cart would price them at the
+	// minimum weight, so every requested product must have a cart line.
+	requested := map[int]bool{}
+	for _, id := range productID {
+		requested[id] = true
+	}
+	if found < len(requested) {
+		r.log.Warn("Repository: product not in cart", zap.Int("user_id", userID), zap.Ints("product_ids", productID))
+		return nil, ErrProductNotInCart
+	}
+	if address != nil {
+		summary.AddressID = address.ID
+		summary.Address = address.String()
+	}
+
+	return &summary, nil
+}
+
+// findAddress returns the user's address with the given id, or their default
+// address when addressID is 0. It returns nil when nothing matches.
+func findAddress(ctx context.Context, q contextQuerier, userID, addressID int) (*model.Address, error) {
+	query := `
+	SELECT a.id, a.user_id, a.label, a.recipient, a.phone, a.street, a.city, a.province, a.postal_code,
+		(a.id = u.default_address_id) IS TRUE AS is_default, a.created_at, a.updated_at
+	FROM addresses a
+	JOIN users u ON u.id = a.user_id
+	WHERE a.user_id = $1 AND ($2 = 0 OR a.id = $2)
+	ORDER BY is_default DESC, a.id ASC
+	LIMIT 1
+	`
+	var address model.Address
+	err := q.QueryRowContext(ctx, query, userID, addressID).Scan(&address.ID, &address.UserID, &address.Label, &address.Recipient, &address.Phone,
+		&address.Street, &address.City, &address.Province, &address.PostalCode, &address.IsDefault, &address.CreatedAt, &address.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// decodeAddress reads the address snapshot of an order. Orders placed before
+// addresses had ids have no snapshot.
+func decodeAddress(data []byte) (*model.Address, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var address model.Address
+	if err := json.Unmarshal(data, &address); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+
+// CreateOrder reprices the selected lines first, locking them for the rest
+// of the transaction so the order is charged at the prices just checked. If
+// any line changed, nothing is ordered: the transaction is rolled back, the
+// new prices are saved to the cart for the customer to confirm, and the
+// notices are returned instead of an order.
+func (r *checkoutRepository) CreateOrder(order model.CreateOrderRequest, quote *model.ShippingQuote) (*model.OrderResponse, []model.CartNotice, error) {
+	userID, productID, couponCode := order.UserID, order.ProductID, order.CouponCode
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	notices, err := repriceCart(ctx, tx, r.log, userID, productID)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, err
+	}
+	if len(notices) > 0 {
+		tx.Rollback()
+		if _, err := saveRepricedCart(ctx, r.db, r.log, userID, productID); err != nil {
+			return nil, nil, err
+		}
+		r.log.Info("Repository: order refused, cart prices changed", zap.Int("user_id", userID), zap.Int("changes", len(notices)))
+		return nil, notices, nil
+	}
+
+	address, err := findAddress(ctx, tx, userID, order.AddressID)
+	if err != nil {
+		tx.Rollback()
+		r.log.Error("Repository: failed to fetch address", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to fetch address %d: %w", order.AddressID, err)
+	}
+	if address == nil {
+		tx.Rollback()
+		r.log.Warn("Repository: Address not found", zap.Int("user_id", userID), zap.Int("address_id", order.AddressID))
+		return nil, nil, fmt.Errorf("address not found")
+	}
+
+	// The address is copied onto the order so later edits to the address
+	// book do not change where an order was shipped.
+	addressJSON, err := json.Marshal(address)
+	if err != nil {
+		tx.Rollback()
+		return nil, nil, fmt.Errorf("failed to serialize address: %w", err)
+	}
+	shippingAddress := address.String()
+
+	r.log.Info("Repository: Fetching shipping address", zap.Int("user_id", userID), zap.Int("address_id", address.ID), zap.String("address shipping", shippingAddress))
+
+	var coupon *model.CouponPreview
+	var discount float64
+	if couponCode != "" {
+		coupon, err = evaluateCoupon(ctx, tx, r.log, userID, couponCode, productID, true)
+		if err != nil {
+			tx.Rollback()
+			return nil, nil, err
+		}
+		discount = coupon.Discount
+	}
+
+	shippingCost := quote.Cost
+	if coupon != nil && coupon.FreeShipping {
+		shippingCost = 0
+	}
+
+	var orderID int
+	insertOrderQuery := `
+        WITH selected_items AS (
+            SELECT 
+                c.user_id,
+                SUM(ci.quantity * ci.price) AS total_price
+            FROM 
+                cart_items ci
+            JOIN 
+                carts c ON c.id = ci.cart_id
+            WHERE 
+                c.user_id = $1
+                AND ci.product_id = ANY($2)
+            GROUP BY c.user_id
+        )
+        INSERT INTO orders (user_id, total_amount, address_id, shipping_address, shipping_address_detail, discount_amount, coupon_code, shipping, shipping_cost)
+        SELECT si.user_id, si.total_price - $4 + $7, $3, $8, $9, $4, NULLIF($5, ''), $6, $7
+        FROM selected_items si
+        RETURNING id, status, created_at, shipping, coupon_code;
+    `
+
+	var status, shipping string
+	var createdAt time.Time
+	var appliedCode *string
+	err = tx.QueryRowContext(ctx, insertOrderQuery, userID, pq.Array(productID), address.ID, discount, couponCode, quote.Method, shippingCost, shippingAddress, addressJSON).
+		Scan(&orderID, &status, &createdAt, &shipping, &appliedCode)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: no cart items selected for order", zap.Int("user_id", userID))
+			return nil, nil, fmt.Errorf("cart not found")
+		}
+		r.log.Error("Repository: failed to create order", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to create order: %w", err)
+	}
+
+	// Order lines are a snapshot of the cart: later product renames or
+	// price changes do not alter what was bought.
+	insertItemsQuery := `
+        INSERT INTO order_items (order_id, user_id, product_id, product_name, image, variant, quantity, price, total)
+        SELECT $1, c.user_id, ci.product_id, p.name, p.images->>0, ci.variant, ci.quantity, ci.price, ci.quantity * ci.price
+        FROM cart_items ci
+        JOIN carts c ON c.id = ci.cart_id
+        JOIN products p ON p.id = ci.product_id
+        WHERE c.user_id = $2
+          AND ci.product_id = ANY($3)
+        ORDER BY ci.id ASC
+        RETURNING product_name, image, variant, quantity, total;
+    `
+	rows, err := tx.QueryContext(ctx, insertItemsQuery, orderID, userID, pq.Array(productID))
+	if err != nil {
+		tx.Rollback()
+		r.log.Error("Repository: failed to create order items", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to create order items: %w", err)
+	}
+
+	var items []model.OrderItem
+	var totalAmount float64
+
+	for rows.Next() {
+		var item model.OrderItem
+		var variantJSON []byte
+		if err := rows.Scan(&item.ProductName, &item.Image, &variantJSON, &item.Quantity, &item.SubtotalPrice); err != nil {
+			rows.Close()
+			tx.Rollback()
+			r.log.Error("Repository: failed to scan order item", zap.Error(err))
+			return nil, nil, fmt.Errorf("failed to scan order item: %w", err)
+		}
+		if err := json.Unmarshal(variantJSON, &item.Variant); err != nil {
+			rows.Close()
+			tx.Rollback()
+			r.log.Error("Repository: failed to unmarshal variant JSON", zap.Error(err))
+			return nil, nil, err
+		}
+		totalAmount += item.SubtotalPrice
+		items = append(items, item)
+	}
+	rows.Close()
+
+	clearCartQuery := `
+        DELETE FROM cart_items ci
+        USING carts c
+        WHERE ci.cart_id = c.id
+          AND c.user_id = $1 
+          AND ci.product_id = ANY($2);
+    `
+	_, err = tx.ExecContext(ctx, clearCartQuery, userID, pq.Array(productID))
+	if err != nil {
+		tx.Rollback()
+		r.log.Error("Repository: failed to clear cart items", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to clear cart items: %w", err)
+	}
+
+	if coupon != nil {
+		redeemQuery := `INSERT INTO coupon_redemptions (coupon_id, user_id, order_id, discount) VALUES ($1, $2, $3, $4)`
+		if _, err := tx.ExecContext(ctx, redeemQuery, coupon.CouponID, userID, orderID, discount); err != nil {
+			tx.Rollback()
+			r.log.Error("Repository: failed to redeem coupon", zap.Error(err))
+			return nil, nil, fmt.Errorf("failed to redeem coupon: %w", err)
+		}
+	}
+
+	historyQuery := `INSERT INTO order_status_histories (order_id, to_status, changed_by) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, historyQuery, orderID, status, userID); err != nil {
+		tx.Rollback()
+		r.log.Error("Repository: failed to record order status", zap.Error(err))
+		return nil, nil, fmt.Errorf("failed to record order status: %w", err)
+	}
+
+	if err := r.reserveStock(ctx, tx, orderID); err != nil {
+		tx.Rollback()
+		r.log.Warn("Repository: failed to reserve stock", zap.Int("order_id", orderID), zap.Error(err))
+		return nil, nil, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, nil, fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	response := &model.OrderResponse{
+		OrderID:               orderID,
+		Items:                 items,
+		Shipping:              shipping,
+		ShippingCost:          shippingCost,
+		CouponCode:            appliedCode,
+		DiscountAmount:        discount,
+		TotalAmount:           totalAmount - discount + shippingCost,
+		ShippingAddress:       &shippingAddress,
+		ShippingAddressDetail: address,
+		Status:                status,
+		CreatedAt:             createdAt,
+	}
+
+	return response, nil, nil
+}
+
+// orderSort lists a user's orders newest first.
+var orderSort = keysetSort{name: "orders", key: "o.created_at", keyType: "timestamp", keyDesc: true, id: "o.id", idDesc: true}
+
+func (r *checkoutRepository) GetAllOrders(userID int, status string, startDate, endDate time.Time, page model.Pagination) ([]*model.OrderResponse, model.Pagination, error) {
+	where := ` WHERE o.user_id = $1`
+	params := []interface{}{userID}
+	paramIndex := 2
+
+	if status != "" {
+		where += ` AND o.status = $` + fmt.Sprint(paramIndex)
+		params = append(params, status)
+		paramIndex++
+	}
+
+	if !startDate.IsZero() {
+		where += ` AND o.created_at >= $` + fmt.Sprint(paramIndex)
+		params = append(params, startDate)
+		paramIndex++
+	}
+
+	if !endDate.IsZero() {
+		where += ` AND o.created_at < $` + fmt.Sprint(paramIndex)
+		params = append(params, endDate)
+		paramIndex++
+	}
+
+	cursor, where, params, err := pageWindow(r.db, r.log, orderSort, &page, `SELECT COUNT(*) FROM orders o`+where, where, params)
+	if err != nil {
+		return nil, page, err
+	}
+
+	query := `
+	SELECT o.id, o.shipping_address, o.shipping_address_detail, o.shipping, o.shipping_cost, o.coupon_code, o.discount_amount, o.total_amount, o.status, o.created_at, ` + orderSort.markColumns() + `
+	FROM orders o` + where
+	query, params = pageLimit(query, orderSort, cursor, page, params)
+
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		r.log.Error("Repository: failed to execute query", zap.Error(err))
+		return nil, page, err
+	}
+	defer rows.Close()
+
+	r.log.Info("Repository: executed query", zap.String("query", query), zap.Any("params", params))
+
+	var results []*model.OrderResponse
+	var marks []keysetMark
+	for rows.Next() {
+		var result model.OrderResponse
+		var addressJSON []byte
+		var mark keysetMark
+		if err := rows.Scan(&result.OrderID, &result.ShippingAddress, &addressJSON, &result.Shipping, &result.ShippingCost, &result.CouponCode, &result.DiscountAmount, &result.TotalAmount, &result.Status, &result.CreatedAt, &mark.key, &mark.id); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, page, err
+		}
+		if result.ShippingAddressDetail, err = decodeAddress(addressJSON); err != nil {
+			r.log.Error("Repository: failed to unmarshal address JSON", zap.Error(err))
+			return nil, page, err
+		}
+		results = append(results, &result)
+		marks = append(marks, mark)
+	}
+
+	results = results[:orderSort.paginate(&page, cursor, marks)]
+	if cursor != nil && cursor.Backward {
+		slices.Reverse(results)
+	}
+
+	orderIDs := make([]int, 0, len(results))
+	for _, result := range results {
+		orderIDs = append(orderIDs, result.OrderID)
+	}
+	items, err := r.getOrderItems(orderIDs)
+	if err != nil {
+		return nil, page, err
+	}
+	for _, result := range results {
+		result.Items = items[result.OrderID]
+	}
+
+	return results, page, nil
+}
+
+func (r *checkoutRepository) GetOrderByID(id, userID int) (*model.OrderResponse, error) {
+	query := `
+	SELECT o.id, o.shipping_address, o.shipping_address_detail, o.shipping, o.shipping_cost, o.coupon_code, o.discount_amount, o.total_amount, o.status, o.created_at
+	FROM orders o
+	WHERE o.id = $1 AND o.user_id = $2
+	`
+	var result model.OrderResponse
+	var addressJSON []byte
+	err := r.db.QueryRow(query, id, userID).Scan(&result.OrderID, &result.ShippingAddress, &addressJSON, &result.Shipping, &result.ShippingCost, &result.CouponCode, &result.DiscountAmount, &result.TotalAmount, &result.Status, &result.CreatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: No order found for the given userID and id", zap.Int("id", id), zap.Int("userID", userID))
+			return nil, fmt.Errorf("order not found")
+		}
+		r.log.Error("Repository: failed to query order", zap.Error(err))
+		return nil, err
+	}
+	if result.ShippingAddressDetail, err = decodeAddress(addressJSON); err != nil {
+		r.log.Error("Repository: failed to unmarshal address JSON", zap.Error(err))
+		return nil, err
+	}
+
+	items, err := r.getOrderItems([]int{result.OrderID})
+	if err != nil {
+		return nil, err
+	}
+	result.Items = items[result.OrderID]
+
+	history, err := r.getOrderStatusHistory(result.OrderID)
+	if err != nil {
+		return nil, err
+	}
+	result.StatusHistory = history
+
+	return &result, nil
+}
+
+func (r *checkoutRepository) getOrderStatusHistory(orderID int) ([]model.OrderStatusHistory, error) {
+	query := `
+	SELECT from_status, to_status, changed_by, note, created_at
+	FROM order_status_histories
+	WHERE order_id = $1
+	ORDER BY created_at ASC, id ASC
+	`
+	rows, err := r.db.Query(query, orderID)
+	if err != nil {
+		r.log.Error("Repository: failed to query order status history", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []model.OrderStatusHistory
+	for rows.Next() {
+		var result model.OrderStatusHistory
+		if err := rows.Scan(&result.FromStatus, &result.ToStatus, &result.ChangedBy, &result.Note, &result.CreatedAt); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (r *checkoutRepository) GetOrderStatus(id int) (string, error) {
+	var status string
+	err := r.db.QueryRow(`SELECT status FROM orders WHERE id = $1`, id).Scan(&status)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: No order found for the given id", zap.Int("id", id))
+			return "", fmt.Errorf("order not found")
+		}
+		r.log.Error("Repository: failed to query order status", zap.Error(err))
+		return "", err
+	}
+
+	return status, nil
+}
+
+func (r *checkoutRepository) UpdateOrderStatus(id int, fromStatus, toStatus string, changedBy int, note string) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	if err := r.updateOrderStatus(ctx, tx, id, fromStatus, toStatus, changedBy, note); err != nil {
+		tx.Rollback()
+		return err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.log.Info("Repository: order status updated", zap.Int("id", id), zap.String("from", fromStatus), zap.String("to", toStatus))
+	return nil
+}
+
+// updateOrderStatus applies a status change inside tx. A changedBy of 0
+// records a system change, e.g. one triggered by a payment webhook.
+func (r *checkoutRepository) updateOrderStatus(ctx context.Context, tx *sql.Tx, id int, fromStatus, toStatus string, changedBy int, note string) error {
+	updateQuery := `
+        UPDATE orders
+        SET status = $3, updated_at = NOW()
+        WHERE id = $1 AND status = $2;
+    `
+	res, err := tx.ExecContext(ctx, updateQuery, id, fromStatus, toStatus)
+	if err != nil {
+		r.log.Error("Repository: failed to update order status", zap.Error(err))
+		return fmt.Errorf("failed to update order status: %w", err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.log.Warn("Repository: order status changed concurrently", zap.Int("id", id), zap.String("from", fromStatus))
+		return ErrOrderStatusChanged
+	}
+
+	historyQuery := `
+        INSERT INTO order_status_histories (order_id, from_status, to_status, changed_by, note)
+        VALUES ($1, $2, $3, NULLIF($4, 0), NULLIF($5, ''));
+    `
+	if _, err := tx.ExecContext(ctx, historyQuery, id, fromStatus, toStatus, changedBy, note); err != nil {
+		r.log.Error("Repository: failed to record order status", zap.Error(err))
+		return fmt.Errorf("failed to record order status: %w", err)
+	}
+
+	switch {
+	case toStatus == model.OrderStatusShipped:
+		err = r.moveStock(ctx, tx, id, "ship")
+	case (toStatus == model.OrderStatusCancelled || toStatus == model.OrderStatusRefunded) &&
+		fromStatus != model.OrderStatusShipped && fromStatus != model.OrderStatusDelivered:
+		err = r.moveStock(ctx, tx, id, "release")
+	}
+	if err != nil {
+		r.log.Error("Repository: failed to update stock", zap.Error(err))
+		return err
+	}
+
+	return nil
+}
+
+func (r *checkoutRepository) getOrderItems(orderIDs []int) (map[int][]model.OrderItem, error) {
+	results := make(map[int][]model.OrderItem)
+	if len(orderIDs) == 0 {
+		return results, nil
+	}
+
+	query := `
+	SELECT oi.order_id, oi.product_name, oi.image, oi.variant, oi.quantity, oi.total
+	FROM order_items oi
+	WHERE oi.order_id = ANY($1)
+	ORDER BY oi.id ASC
+	`
+	rows, err := r.db.Query(query, pq.Array(orderIDs))
+	if err != nil {
+		r.log.Error("Repository: failed to query order items", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var orderID int
+		var item model.OrderItem
+		var variantJSON []byte
+		if err := rows.Scan(&orderID, &item.ProductName, &item.Image, &variantJSON, &item.Quantity, &item.SubtotalPrice); err != nil {
+			r.log.Error("Repository: failed to scan order item", zap.Error(err))
+			return nil, err
+		}
+		if err := json.Unmarshal(variantJSON, &item.Variant); err != nil {
+			r.log.Error("Repository: failed to unmarshal variant JSON", zap.Error(err))
+			return nil, err
+		}
+		results[orderID] = append(results[orderID], item)
+	}
+
+	return results, nil
+}
+
+func (r *checkoutRepository) reserveStock(ctx context.Context, tx *sql.Tx, orderID int) error {
+	rows, err := tx.QueryContext(ctx, `SELECT product_id, variant, quantity FROM order_items WHERE order_id = $1`, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch order items: %w", err)
+	}
+
+	type line struct {
+		productID int
+		variant   []byte
+		quantity  int
+	}
+	var lines []line
+	for rows.Next() {
+		var l line
+		if err := rows.Scan(&l.productID, &l.variant, &l.quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan order item: %w", err)
+		}
+		lines = append(lines, l)
+	}
+	rows.Close()
+
+	// Variants without their own inventory row draw from the product-wide row.
+	reserveQuery := `
+        UPDATE inventories
+        SET reserved = reserved + $2, updated_at = NOW()
+        WHERE id = (
+                SELECT id FROM inventories
+                WHERE product_id = $1 AND variant IN ($3::jsonb, '{}'::jsonb)
+                ORDER BY variant = '{}'::jsonb
+                LIMIT 1
+            )
+          AND quantity - reserved >= $2
+        RETURNING id;
+    `
+	movementQuery := `INSERT INTO stock_movements (inventory_id, order_id, change, reason) VALUES ($1, $2, $3, 'reserve')`
+
+	for _, l := range lines {
+		var inventoryID int
+		err := tx.QueryRowContext(ctx, reserveQuery, l.productID, l.quantity, l.variant).Scan(&inventoryID)
+		if err != nil {
+			if errors.Is(err, sql.ErrNoRows) {
+				return fmt.Errorf("%w for product %d", ErrInsufficientStock, l.productID)
+			}
+			return fmt.Errorf("failed to reserve stock: %w", err)
+		}
+
+		if _, err := tx.ExecContext(ctx, movementQuery, inventoryID, orderID, -l.quantity); err != nil {
+			return fmt.Errorf("failed to record stock movement: %w", err)
+		}
+	}
+
+	r.log.Info("Repository: stock reserved", zap.Int("order_id", orderID), zap.Int("lines", len(lines)))
+	return nil
+}
+
+// moveStock settles the stock reserved for an order. "ship" removes the
+// reserved units from the shelf and "release" puts them back on sale.
+func (r *checkoutRepository) moveStock(ctx context.Context, tx *sql.Tx, orderID int, reason string) error {
+	rows, err := tx.QueryContext(ctx, `
+        SELECT inventory_id, -SUM(change)
+        FROM stock_movements
+        WHERE order_id = $1 AND reason = 'reserve'
+        GROUP BY inventory_id
+    `, orderID)
+	if err != nil {
+		return fmt.Errorf("failed to fetch reserved stock: %w", err)
+	}
+
+	reserved := make(map[int]int)
+	for rows.Next() {
+		var inventoryID, quantity int
+		if err := rows.Scan(&inventoryID, &quantity); err != nil {
+			rows.Close()
+			return fmt.Errorf("failed to scan reserved stock: %w", err)
+		}
+		reserved[inventoryID] = quantity
+	}
+	rows.Close()
+
+	updateQuery := `UPDATE inventories SET reserved = reserved - $2, updated_at = NOW() WHERE id = $1`
+	change := 1
+	if reason == "ship" {
+		updateQuery = `UPDATE inventories SET quantity = quantity - $2, reserved = reserved - $2, updated_at = NOW() WHERE id = $1`
+		change = -1
+	}
+	movementQuery := `INSERT INTO stock_movements (inventory_id, order_id, change, reason) VALUES ($1, $2, $3, $4)`
+
+	for inventoryID, quantity := range reserved {
+		if _, err := tx.ExecContext(ctx, updateQuery, inventoryID, quantity); err != nil {
+			return fmt.Errorf("failed to update stock: %w", err)
+		}
+		if _, err := tx.ExecContext(ctx, movementQuery, inventoryID, orderID, change*quantity, reason); err != nil {
+			return fmt.Errorf("failed to record stock movement: %w", err)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,280 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ecommerce/model"
+	"errors"
+	"fmt"
+	"math"
+	"time"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrCouponNotFound = errors.New("coupon not found")
+	ErrCouponInvalid  = errors.New("coupon cannot be applied")
+)
+
+type CouponRepository interface {
+	CreateCoupon(coupon *model.Coupon) error
+	GetAllCoupons() ([]*model.Coupon, error)
+	DeactivateCoupon(id int) error
+	PreviewCoupon(userID int, code string, productIDs []int) (*model.CouponPreview, error)
+}
+
+type couponRepository struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewCouponRepository(db *sql.DB, logger *zap.Logger) CouponRepository {
+	return &couponRepository{db: db, log: logger}
+}
+
+type contextQuerier interface {
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...interface{}) *sql.Row
+}
+
+func (r *couponRepository) CreateCoupon(coupon *model.Coupon) error {
+	if coupon.StartsAt.IsZero() {
+		coupon.StartsAt = time.Now()
+	}
+	query := `
+        INSERT INTO coupons (code, type, value, min_spend, max_discount, usage_limit, per_user_limit, starts_at, ends_at, product_ids, category_ids)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11)
+        RETURNING id, active, created_at
+    `
+	err := r.db.QueryRow(query, coupon.Code, coupon.Type, coupon.Value, coupon.MinSpend, coupon.MaxDiscount, coupon.UsageLimit,
+		coupon.PerUserLimit, coupon.StartsAt, coupon.EndsAt, pq.Array(intsOrEmpty(coupon.ProductIDs)), pq.Array(intsOrEmpty(coupon.CategoryIDs))).
+		Scan(&coupon.ID, &coupon.Active, &coupon.CreatedAt)
+	if err != nil {
+		var pqErr *pq.Error
+		if errors.As(err, &pqErr) && pqErr.Code == "23505" {
+			r.log.Warn("Repository: coupon code already exists", zap.String("code", coupon.Code))
+			return fmt.Errorf("coupon code already exists")
+		}
+		r.log.Error("Repository: failed to create coupon", zap.Error(err))
+		return fmt.Errorf("failed to create coupon: %w", err)
+	}
+
+	r.log.Info("Repository: coupon created", zap.Int("id", coupon.ID), zap.String("code", coupon.Code))
+	return nil
+}
+
+func (r *couponRepository) GetAllCoupons() ([]*model.Coupon, error) {
+	query := `
+	SELECT c.id, c.code, c.type, c.value, c.min_spend, c.max_discount, c.usage_limit, c.per_user_limit,
+		c.starts_at, c.ends_at, c.product_ids, c.category_ids, c.active, c.created_at,
+		(SELECT COUNT(*) FROM coupon_redemptions cr JOIN orders o ON o.id = cr.order_id WHERE cr.coupon_id = c.id AND o.status <> 'cancelled')
+	FROM coupons c
+	ORDER BY c.created_at DESC, c.id DESC
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		r.log.Error("Repository: failed to query coupons", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.Coupon
+	for rows.Next() {
+		var coupon model.Coupon
+		var productIDs, categoryIDs pq.Int64Array
+		if err := rows.Scan(&coupon.ID, &coupon.Code, &coupon.Type, &coupon.Value, &coupon.MinSpend, &coupon.MaxDiscount, &coupon.UsageLimit, &coupon.PerUserLimit,
+			&coupon.StartsAt, &coupon.EndsAt, &productIDs, &categoryIDs, &coupon.Active, &coupon.CreatedAt, &coupon.TimesUsed); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		coupon.ProductIDs = toInts(productIDs)
+		coupon.CategoryIDs = toInts(categoryIDs)
+		results = append(results, &coupon)
+	}
+
+	return results, nil
+}
+
+func (r *couponRepository) DeactivateCoupon(id int) error {
+	res, err := r.db.Exec(`UPDATE coupons SET active = false WHERE id = $1`, id)
+	if err != nil {
+		r.log.Error("Repository: failed to deactivate coupon", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		return ErrCouponNotFound
+	}
+
+	r.log.Info("Repository: coupon deactivated", zap.Int("id", id))
+	return nil
+}
+
+func (r *couponRepository) PreviewCoupon(userID int, code string, productIDs []int) (*model.CouponPreview, error) {
+	return evaluateCoupon(context.Background(), r.db, r.log, userID, code, productIDs, false)
+}
+
+type couponLine struct {
+	productID  int
+	categoryID int
+	total      float64
+}
+
+// evaluateCoupon prices code against the user's cart lines for productIDs
+// (the whole cart when empty). With lock set the coupon row is locked so
+// usage limits hold while an order redeems it.
+func evaluateCoupon(ctx context.Context, q contextQuerier, log *zap.Logger, userID int, code string, productIDs []int, lock bool) (*model.CouponPreview, error) {
+	coupon, used, usedByUser, err := loadCoupon(ctx, q, log, userID, code, lock)
+	if err != nil {
+		return nil, err
+	}
+
+	linesQuery := `
+	SELECT ci.product_id, COALESCE(p.category_id, 0), ci.quantity * ci.price
+	FROM cart_items ci
+	JOIN carts c ON c.id = ci.cart_id
+	JOIN products p ON p.id = ci.product_id
+	WHERE c.user_id = $1
+	  AND (COALESCE(cardinality($2::int[]), 0) = 0 OR ci.product_id = ANY($2))
+	`
+	rows, err := q.QueryContext(ctx, linesQuery, userID, pq.Array(productIDs))
+	if err != nil {
+		log.Error("Repository: failed to query cart lines", zap.Error(err))
+		return nil, err
+	}
+	var lines []couponLine
+	for rows.Next() {
+		var line couponLine
+		if err := rows.Scan(&line.productID, &line.categoryID, &line.total); err != nil {
+			rows.Close()
+			log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	rows.Close()
+
+	return priceCoupon(coupon, lines, used, usedByUser, time.Now())
+}
+
+// loadCoupon reads the coupon for code with how often it has been used in
+// total and by userID.
+func loadCoupon(ctx context.Context, q contextQuerier, log *zap.Logger, userID int, code string, lock bool) (*model.Coupon, int, int, error) {
+	query := `
+	SELECT id, code, type, value, min_spend, max_discount, usage_limit, per_user_limit, starts_at, ends_at, product_ids, category_ids, active
+	FROM coupons
+	WHERE upper(code) = upper($1)
+	`
+	if lock {
+		query += ` FOR UPDATE`
+	}
+
+	var coupon model.Coupon
+	var scopeProducts, scopeCategories pq.Int64Array
+	err := q.QueryRowContext(ctx, query, code).Scan(&coupon.ID, &coupon.Code, &coupon.Type, &coupon.Value, &coupon.MinSpend, &coupon.MaxDiscount,
+		&coupon.UsageLimit, &coupon.PerUserLimit, &coupon.StartsAt, &coupon.EndsAt, &scopeProducts, &scopeCategories, &coupon.Active)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			log.Warn("Repository: coupon not found", zap.String("code", code))
+			return nil, 0, 0, ErrCouponNotFound
+		}
+		log.Error("Repository: failed to query coupon", zap.Error(err))
+		return nil, 0, 0, err
+	}
+	coupon.ProductIDs = toInts(scopeProducts)
+	coupon.CategoryIDs = toInts(scopeCategories)
+
+	// Redemptions of cancelled orders give the usage back.
+	var used, usedByUser int
+	usageQuery := `
+	SELECT COUNT(*), COUNT(*) FILTER (WHERE cr.user_id = $2)
+	FROM coupon_redemptions cr
+	JOIN orders o ON o.id = cr.order_id
+	WHERE cr.coupon_id = $1 AND o.status <> 'cancelled'
+	`
+	if err := q.QueryRowContext(ctx, usageQuery, coupon.ID, userID).Scan(&used, &usedByUser); err != nil {
+		log.Error("Repository: failed to count coupon usage", zap.Error(err))
+		return nil, 0, 0, err
+	}
+
+	return &coupon, used, usedByUser, nil
+}
+
+func priceCoupon(coupon *model.Coupon, lines []couponLine, used, usedByUser int, now time.Time) (*model.CouponPreview, error) {
+	switch {
+	case !coupon.Active:
+		return nil, fmt.Errorf("%w: coupon is no longer active", ErrCouponInvalid)
+	case now.Before(coupon.StartsAt):
+		return nil, fmt.Errorf("%w: coupon is not valid yet", ErrCouponInvalid)
+	case now.After(coupon.EndsAt):
+		return nil, fmt.Errorf("%w: coupon has expired", ErrCouponInvalid)
+	case coupon.UsageLimit != nil && used >= *coupon.UsageLimit:
+		return nil, fmt.Errorf("%w: coupon usage limit reached", ErrCouponInvalid)
+	case coupon.PerUserLimit != nil && usedByUser >= *coupon.PerUserLimit:
+		return nil, fmt.Errorf("%w: you have already used this coupon", ErrCouponInvalid)
+	case len(lines) == 0:
+		return nil, fmt.Errorf("%w: cart is empty", ErrCouponInvalid)
+	}
+
+	result := &model.CouponPreview{CouponID: coupon.ID, Code: coupon.Code, Type: coupon.Type}
+	scoped := len(coupon.ProductIDs) > 0 || len(coupon.CategoryIDs) > 0
+	for _, line := range lines {
+		result.Subtotal += line.total
+		if !scoped || containsInt(coupon.ProductIDs, line.productID) || containsInt(coupon.CategoryIDs, line.categoryID) {
+			result.EligibleSubtotal += line.total
+		}
+	}
+
+	if result.EligibleSubtotal == 0 {
+		return nil, fmt.Errorf("%w: coupon does not apply to the selected products", ErrCouponInvalid)
+	}
+	// A scoped coupon's minimum spend counts only the products it applies to.
+	if result.EligibleSubtotal < coupon.MinSpend {
+		return nil, fmt.Errorf("%w: minimum spend on eligible products is %.2f", ErrCouponInvalid, coupon.MinSpend)
+	}
+
+	switch coupon.Type {
+	case model.CouponTypePercentage:
+		result.Discount = result.EligibleSubtotal * coupon.Value / 100
+		if coupon.MaxDiscount != nil && result.Discount > *coupon.MaxDiscount {
+			result.Discount = *coupon.MaxDiscount
+		}
+	case model.CouponTypeFixed:
+		result.Discount = math.Min(coupon.Value, result.EligibleSubtotal)
+	case model.CouponTypeFreeShipping:
+		result.FreeShipping = true
+	}
+	// Never discount more than the goods the coupon applies to.
+	result.Discount = math.Min(result.Discount, result.EligibleSubtotal)
+	result.Discount = math.Round(result.Discount*100) / 100
+	result.Total = result.Subtotal - result.Discount
+
+	return result, nil
+}
+
+func containsInt(values []int, value int) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
+func toInts(values pq.Int64Array) []int {
+	results := make([]int, len(values))
+	for i, v := range values {
+		results[i] = int(v)
+	}
+	return results
+}
+
+func intsOrEmpty(values []int) []int {
+	if values == nil {
+		return []int{}
+	}
+	return values
+}
@@ -1,388 +1,724 @@
-package repository
-
-import (
-	"database/sql"
-	"ecommerce/model"
-	"encoding/json"
-	"errors"
-	"fmt"
-
-	"go.uber.org/zap"
-)
-
-type HomePageRepository interface {
-	GetAllProducts(name string, categoryID, limit, page int) ([]*model.Product, int, int, error)
-	GetByIdProduct(id int) (*model.ProductID, error)
-	GetAllCategories() ([]*model.Category, error)
-	GetAllBanners() ([]*model.BannerWeeklyPromotionRecomment, error)
-	GetAllBestSellingProducts(limit, page int) ([]*model.Product, int, int, error)
-	GetAllWeeklyPromotionProducts() ([]*model.BannerWeeklyPromotionRecomment, error)
-	GetAllRecommentsProducts() ([]*model.BannerWeeklyPromotionRecomment, error)
-	AddWishlist(wishlist model.Wishlist) error
-	DeleteWishlist(id, userID int) error
-}
-
-type homePageRepository struct {
-	db  *sql.DB
-	log *zap.Logger
-}
-
-func NewHomePageRepository(db *sql.DB, logger *zap.Logger) HomePageRepository {
-	return &homePageRepository{db: db, log: logger}
-}
-
-func (r *homePageRepository) GetAllProducts(name string, categoryID, limit, page int) ([]*model.Product, int, int, error) {
-
-	query := `
-	SELECT p.id, p.name, p.images->>0 AS thumbnail_image, p.price, 
-	COALESCE(CASE WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-	ELSE 0 
-	END, 0) AS discount_percentage,
- 		CASE 
-    WHEN COALESCE(
-    CASE 
-        WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-        ELSE 0
-    END, 0) = 0 THEN 0
-    ELSE CAST(p.price AS FLOAT) * (1 - CAST(COALESCE(
-    CASE 
-        WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-        ELSE 0
-    END, 0) AS FLOAT) / 100.0) END AS discount_price,  
-	COALESCE(AVG(r.rating), 0) as average_rating,
-	COUNT(DISTINCT oi.order_id) AS sold,
-	CASE WHEN CURRENT_DATE - p.created_at <= INTERVAL '30 days' THEN TRUE ELSE FALSE
-    END AS is_new
-	FROM products p
-	JOIN categories c ON p.category_id = c.id
-	LEFT JOIN ratings r ON p.id = r.product_id
-	LEFT JOIN order_items oi ON p.id = oi.product_id
-	LEFT JOIN weekly_promotions wp ON p.id = wp.product_id
-	WHERE 1=1
-	`
-
-	countQuery := `
-		SELECT COUNT(*) 
-		FROM products p
-		JOIN categories c ON p.category_id = c.id
-		WHERE 1=1
-	`
-
-	var params []interface{}
-	paramIndex := 1
-
-	if name != "" {
-		query += ` AND p.name ILIKE $` + fmt.Sprint(paramIndex)
-		countQuery += ` AND p.name ILIKE $` + fmt.Sprint(paramIndex)
-		params = append(params, "%"+name+"%")
-		paramIndex++
-	}
-
-	if categoryID > 0 {
-		query += ` AND p.category_id = $` + fmt.Sprint(paramIndex)
-		countQuery += ` AND p.category_id = $` + fmt.Sprint(paramIndex)
-		params = append(params, categoryID)
-		paramIndex++
-	}
-
-	query += ` GROUP BY p.id, p.name, p.images, p.price, wp.discount_percentage, p.created_at`
-	query += ` ORDER BY p.id ASC`
-
-	var totalItems int
-	err := r.db.QueryRow(countQuery, params...).Scan(&totalItems)
-	if err != nil {
-		r.log.Error("Repository: failed to execute count query", zap.Error(err))
-		return nil, 0, 0, err
-	}
-
-	totalPages := (totalItems + limit - 1) / limit
-	offset := (page - 1) * limit
-	query += ` LIMIT $` + fmt.Sprint(paramIndex) + ` OFFSET $` + fmt.Sprint(paramIndex+1)
-	params = append(params, limit, offset)
-
-	rows, err := r.db.Query(query, params...)
-	if err != nil {
-		r.log.Error("Repository: failed to execute query", zap.Error(err))
-		return nil, 0, 0, err
-	}
-	defer rows.Close()
-
-	r.log.Info("Repository: executed query", zap.String("query", query), zap.Any("params", params))
-
-	var results []*model.Product
-	for rows.Next() {
-		var result model.Product
-		if err := rows.Scan(&result.ID, &result.Name, &result.ThumbnailImage, &result.Price, &result.Discount, &result.DiscountPrice,
-			&result.AverageRating, &result.Sold, &result.IsNEW); err != nil {
-			r.log.Error("Repository: failed to scan row", zap.Error(err))
-			return nil, 0, 0, err
-		}
-		results = append(results, &result)
-	}
-
-	return results, totalItems, totalPages, nil
-}
-
-func (r *homePageRepository) GetByIdProduct(id int) (*model.ProductID, error) {
-	var product model.ProductID
-	var imagesJSON []byte
-	var variantJSON []byte
-
-	query := `
-	SELECT p.id, p.name, p.images, c.name as category_name, p.price,
-	CASE WHEN c.variant = '{}' THEN NULL ELSE c.variant END AS variant,
-    COALESCE(CASE WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-	ELSE 0 
-	END, 0) AS discount_percentage,
- 		CASE 
-    WHEN COALESCE(
-    CASE 
-        WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-        ELSE 0
-    END, 0) = 0 THEN 0
-    ELSE CAST(p.price AS FLOAT) * (1 - CAST(COALESCE(
-    CASE 
-        WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-        ELSE 0
-    END, 0) AS FLOAT) / 100.0) END AS discount_price,
-    COALESCE(AVG(r.rating), 0) AS average_rating,
-    COUNT(DISTINCT oi.order_id) AS sold,
-	CASE WHEN CURRENT_DATE - p.created_at <= INTERVAL '30 days' THEN TRUE ELSE FALSE
-    END AS is_new
-	FROM products p
-	JOIN categories c ON p.category_id = c.id
-	LEFT JOIN ratings r ON p.id = r.product_id
-	LEFT JOIN order_items oi ON p.id = oi.product_id
-	LEFT JOIN weekly_promotions wp ON p.id = wp.product_id
-	WHERE p.id = $1
-	GROUP BY p.id, p.name, p.images, c."name", p.price, c.variant, wp.discount_percentage, p.created_at
-	ORDER BY p.id ASC;
-	`
-	err := r.db.QueryRow(query, id).Scan(&product.ID, &product.Name, &imagesJSON, &product.Category, &product.Price, &variantJSON, &product.Discount, &product.DiscountPrice, &product.AverageRating, &product.Sold, &product.IsNEW)
-	if err != nil {
-		if errors.Is(err, sql.ErrNoRows) {
-			return nil, nil
-		}
-		r.log.Error("Repository: failed to scan row", zap.Error(err))
-		return nil, err
-	}
-
-	if len(imagesJSON) > 0 {
-		if err := json.Unmarshal(imagesJSON, &product.Images); err != nil {
-			r.log.Error("Repository: failed to unmarshal images JSON", zap.Error(err))
-			return nil, err
-		}
-	}
-	if len(variantJSON) > 0 {
-		if err := json.Unmarshal(variantJSON, &product.Variant); err != nil {
-			r.log.Error("Repository: failed to unmarshal variant JSON", zap.Error(err))
-			return nil, err
-		}
-	}
-
-	return &product, nil
-}
-
-func (r *homePageRepository) GetAllCategories() ([]*model.Category, error) {
-	rows, err := r.db.Query(`SELECT id, name FROM categories`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var categories []*model.Category
-	for rows.Next() {
-		var category model.Category
-		if err := rows.Scan(&category.ID, &category.Name); err != nil {
-			return nil, err
-		}
-		categories = append(categories, &category)
-	}
-
-	return categories, nil
-}
-
-func (r *homePageRepository) GetAllBanners() ([]*model.BannerWeeklyPromotionRecomment, error) {
-	rows, err := r.db.Query(`SELECT id, image, title, subtitle, path_page FROM banners`)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []*model.BannerWeeklyPromotionRecomment
-	for rows.Next() {
-		var result model.BannerWeeklyPromotionRecomment
-		if err := rows.Scan(&result.ID, &result.Image, &result.Title, &result.Subtitle, &result.PathPage); err != nil {
-			r.log.Error("Repository: failed to scan row", zap.Error(err))
-			return nil, err
-		}
-		results = append(results, &result)
-	}
-
-	return results, nil
-}
-
-func (r *homePageRepository) GetAllBestSellingProducts(limit, page int) ([]*model.Product, int, int, error) {
-
-	query := `
-	SELECT p.id, p.name, p.images->>0 AS thumbnail_image, p.price, 
-    COALESCE(CASE WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-	ELSE 0 
-	END, 0) AS discount_percentage,
- 		CASE 
-    WHEN COALESCE(
-    CASE 
-        WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-        ELSE 0
-    END, 0) = 0 THEN 0
-    ELSE CAST(p.price AS FLOAT) * (1 - CAST(COALESCE(
-    CASE 
-        WHEN MAX(wp.start_date) <= CURRENT_DATE AND MAX(wp.end_date) >= CURRENT_DATE THEN wp.discount_percentage
-        ELSE 0
-    END, 0) AS FLOAT) / 100.0) END AS discount_price,
-    COALESCE(AVG(r.rating), 0) AS average_rating,
-    COUNT(DISTINCT oi.order_id) AS sold,
-	CASE WHEN CURRENT_DATE - p.created_at <= INTERVAL '30 days' THEN TRUE ELSE FALSE
-    END AS is_new 
-	FROM products p
-	JOIN categories c ON p.category_id = c.id
-	LEFT JOIN ratings r ON p.id = r.product_id
-	LEFT JOIN order_items oi ON p.id = oi.product_id
-	LEFT JOIN orders o ON oi.order_id = o.id
-	LEFT JOIN weekly_promotions wp ON p.id = wp.product_id
-	WHERE DATE_TRUNC('month', o.created_at) = DATE_TRUNC('month', CURRENT_DATE)
-	`
-
-	countQuery := `
-		SELECT COUNT(*)
-        FROM (
-            SELECT p.id
-            FROM products p
-            JOIN categories c ON p.category_id = c.id
-            LEFT JOIN order_items oi ON p.id = oi.product_id
-            LEFT JOIN orders o ON oi.order_id = o.id
-            WHERE DATE_TRUNC('month', o.created_at) = DATE_TRUNC('month', CURRENT_DATE)
-            GROUP BY p.id
-        ) AS filtered_products
-	`
-	var params []interface{}
-	paramIndex := 1
-
-	query += ` GROUP BY p.id, p.name, p.images, p.price, wp.discount_percentage, p.created_at`
-	query += ` ORDER BY sold DESC, p.id ASC`
-
-	var totalItems int
-	err := r.db.QueryRow(countQuery, params...).Scan(&totalItems)
-	if err != nil {
-		r.log.Error("Repository: failed to execute count query", zap.Error(err))
-		return nil, 0, 0, err
-	}
-
-	totalPages := (totalItems + limit - 1) / limit
-	offset := (page - 1) * limit
-	query += ` LIMIT $` + fmt.Sprint(paramIndex) + ` OFFSET $` + fmt.Sprint(paramIndex+1)
-	params = append(params, limit, offset)
-
-	rows, err := r.db.Query(query, params...)
-	if err != nil {
-		r.log.Error("Repository: failed to execute query", zap.Error(err))
-		return nil, 0, 0, err
-	}
-	defer rows.Close()
-
-	r.log.Info("Repository: executed query", zap.String("query", query), zap.Any("params", params))
-
-	var results []*model.Product
-	for rows.Next() {
-		var result model.Product
-		if err := rows.Scan(&result.ID, &result.Name, &result.ThumbnailImage, &result.Price, &result.Discount, &result.DiscountPrice,
-			&result.AverageRating, &result.Sold, &result.IsNEW); err != nil {
-			r.log.Error("Repository: failed to scan row", zap.Error(err))
-			return nil, 0, 0, err
-		}
-		results = append(results, &result)
-	}
-
-	return results, totalItems, totalPages, nil
-}
-
-func (r *homePageRepository) GetAllWeeklyPromotionProducts() ([]*model.BannerWeeklyPromotionRecomment, error) {
-	query := `
-	SELECT p.id, p.images->>0 AS thumbnail_image, p.title, p.subtitle 
-	FROM products p
-	JOIN weekly_promotions wp ON p.id = wp.product_id
-	WHERE wp.start_date <= CURRENT_DATE AND wp.end_date >= CURRENT_DATE
-	`
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []*model.BannerWeeklyPromotionRecomment
-	for rows.Next() {
-		var result model.BannerWeeklyPromotionRecomment
-		if err := rows.Scan(&result.ID, &result.Image, &result.Title, &result.Subtitle); err != nil {
-			r.log.Error("Repository: failed to scan row", zap.Error(err))
-			return nil, err
-		}
-		results = append(results, &result)
-	}
-
-	return results, nil
-}
-func (r *homePageRepository) GetAllRecommentsProducts() ([]*model.BannerWeeklyPromotionRecomment, error) {
-	query := `
-	SELECT p.id, p.images->>0 AS thumbnail_image, p.title, p.subtitle
-	FROM products p
-	JOIN recomments r ON p.id = r.product_id
-	ORDER BY p.id ASC;
-	`
-	rows, err := r.db.Query(query)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []*model.BannerWeeklyPromotionRecomment
-	for rows.Next() {
-		var result model.BannerWeeklyPromotionRecomment
-		if err := rows.Scan(&result.ID, &result.Image, &result.Title, &result.Subtitle); err != nil {
-			r.log.Error("Repository: failed to scan row", zap.Error(err))
-			return nil, err
-		}
-		results = append(results, &result)
-	}
-
-	return results, nil
-}
-
-func (r *homePageRepository) AddWishlist(wishlist model.Wishlist) error {
-	query := `INSERT INTO wishlists (user_id, product_id) VALUES ($1, $2) RETURNING id`
-	err := r.db.QueryRow(query, wishlist.UserID, wishlist.ProductID).Scan(&wishlist.ID)
-	if err != nil {
-		r.log.Error("Repository: Error executing query", zap.Error(err))
-		return fmt.Errorf("product not found or product already exists in wishlist")
-	}
-
-	r.log.Info("Repository: Wishlist added successfully", zap.Int("id", wishlist.ID))
-	return nil
-}
-
-func (r *homePageRepository) DeleteWishlist(id, userID int) error {
-	query := `DELETE FROM wishlists WHERE id = $1 AND user_id = $2`
-	res, err := r.db.Exec(query, id, userID)
-	if err != nil {
-		r.log.Error("Repository: Error executing query", zap.Error(err))
-		return err
-	}
-
-	rowsAffected, _ := res.RowsAffected()
-	if rowsAffected == 0 {
-		r.log.Warn("Repository: No wishlist found for the given userID and id")
-		return fmt.Errorf("no wishlist found")
-	}
-
-	r.log.Info("Repository: Wishlist deleted successfully", zap.Int("id", id), zap.Int("userID", userID))
-	return nil
-}
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ecommerce/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+type HomePageRepository interface {
+	GetAllProducts(filter model.ProductFilter, page model.Pagination) ([]*model.Product, model.Pagination, error)
+	GetProductFacets(filter model.ProductFilter) (*model.ProductFacets, error)
+	SearchProducts(query string, categoryID, limit, page int) ([]*model.SearchProduct, int, int, error)
+	HasSearchMatch(query string, categoryID int) (bool, error)
+	GetSearchSuggestions(query string, limit int) ([]string, error)
+	GetByIdProduct(id int) (*model.ProductID, error)
+	GetAllCategories() ([]*model.Category, error)
+	GetAllBanners() ([]*model.BannerWeeklyPromotionRecomment, error)
+	GetAllBestSellingProducts(page model.Pagination) ([]*model.Product, model.Pagination, error)
+	GetAllWeeklyPromotionProducts() ([]*model.BannerWeeklyPromotionRecomment, error)
+	GetAllRecommentsProducts() ([]*model.BannerWeeklyPromotionRecomment, error)
+	AddWishlist(wishlist model.Wishlist) error
+	DeleteWishlist(id, userID int) error
+	GetAllWishlist(userID int) ([]*model.WishlistItem, error)
+	CreateWishlistShare(userID int, token string) (string, error)
+	DeleteWishlistShare(userID int) error
+	GetSharedWishlist(token string) (*model.SharedWishlist, error)
+}
+
+type homePageRepository struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewHomePageRepository(db *sql.DB, logger *zap.Logger) HomePageRepository {
+	return &homePageRepository{db: db, log: logger}
+}
+
+// productListingQuery yields one row per live product with its current
+// single-unit price, rating and sales folded in, so listings can filter and
+// sort on them without GROUP BY. effective_price is what the customer pays
+// today, as resolved by product_price().
+const productListingQuery = `
+	SELECT p.id, p.name, p.images->>0 AS thumbnail_image, p.price, p.category_id, c.name AS category_name, p.created_at,
+	pr.discount_percentage,
+	CASE WHEN pr.discount_percentage = 0 THEN 0 ELSE CAST(pr.unit_price AS FLOAT) END AS discount_price,
+	CAST(pr.unit_price AS FLOAT) AS effective_price,
+	CAST(COALESCE(rt.average_rating, 0) AS FLOAT) AS average_rating,
+	COALESCE(so.sold, 0) AS sold,
+	CURRENT_DATE - p.created_at <= INTERVAL '30 days' AS is_new
+	FROM products p
+	JOIN categories c ON p.category_id = c.id
+	CROSS JOIN LATERAL product_price(p.id) pr
+	LEFT JOIN LATERAL (SELECT AVG(r.rating) AS average_rating FROM ratings r WHERE r.product_id = p.id) rt ON TRUE
+	LEFT JOIN LATERAL (SELECT COUNT(DISTINCT oi.order_id) AS sold FROM order_items oi WHERE oi.product_id = p.id) so ON TRUE
+	WHERE p.deleted_at IS NULL
+`
+
+const (
+	facetCategory = "category"
+	facetPrice    = "price"
+	facetRating   = "rating"
+)
+
+var productSorts = map[string]keysetSort{
+	"":                    {name: "id", id: "l.id"},
+	model.SortPriceAsc:    {name: model.SortPriceAsc, key: "l.effective_price", keyType: "float8", id: "l.id"},
+	model.SortPriceDesc:   {name: model.SortPriceDesc, key: "l.effective_price", keyType: "float8", keyDesc: true, id: "l.id"},
+	model.SortNewest:      {name: model.SortNewest, key: "l.created_at", keyType: "timestamp", keyDesc: true, id: "l.id", idDesc: true},
+	model.SortRating:      {name: model.SortRating, key: "l.average_rating", keyType: "float8", keyDesc: true, id: "l.id"},
+	model.SortBestSelling: {name: model.SortBestSelling, key: "l.sold", keyType: "bigint", keyDesc: true, id: "l.id"},
+}
+
+// priceRangeEdges splits the price facet; the ranges are [0, 50000),
+// [50000, 100000) and so on, with the last one open ended.
+var priceRangeEdges = []float64{50000, 100000, 250000, 500000, 1000000}
+
+// productFilterClause builds the WHERE clause over the listing alias l,
+// leaving out the filter that belongs to facet skip.
+func productFilterClause(filter model.ProductFilter, skip string) (string, []interface{}) {
+	clause := ` WHERE TRUE`
+	var params []interface{}
+	add := func(condition string, value interface{}) {
+		params = append(params, value)
+		clause += ` AND ` + fmt.Sprintf(condition, len(params))
+	}
+
+	if filter.Name != "" {
+		add(`l.name ILIKE $%d`, "%"+filter.Name+"%")
+	}
+	if filter.CategoryID > 0 && skip != facetCategory {
+		add(`l.category_id = $%d`, filter.CategoryID)
+	}
+	if skip != facetPrice {
+		if filter.MinPrice > 0 {
+			add(`l.effective_price >= $%d`, filter.MinPrice)
+		}
+		if filter.MaxPrice > 0 {
+			add(`l.effective_price <= $%d`, filter.MaxPrice)
+		}
+	}
+	if filter.MinRating > 0 && skip != facetRating {
+		add(`l.average_rating >= $%d`, filter.MinRating)
+	}
+	if filter.OnPromotion {
+		clause += ` AND l.discount_percentage > 0`
+	}
+	if filter.IsNew {
+		clause += ` AND l.is_new`
+	}
+	if len(filter.Variant) > 0 {
+		variantJSON, _ := json.Marshal(filter.Variant)
+		add(`EXISTS (SELECT 1 FROM inventories i WHERE i.product_id = l.id AND i.variant @> $%d::jsonb AND i.quantity > i.reserved)`, string(variantJSON))
+	}
+
+	return clause, params
+}
+
+func (r *homePageRepository) GetAllProducts(filter model.ProductFilter, page model.Pagination) ([]*model.Product, model.Pagination, error) {
+	sort, ok := productSorts[filter.Sort]
+	if !ok {
+		return nil, page, fmt.Errorf("invalid sort")
+	}
+
+	where, params := productFilterClause(filter, "")
+	cursor, where, params, err := pageWindow(r.db, r.log, sort, &page, `SELECT COUNT(*) FROM (`+productListingQuery+`) l`+where, where, params)
+	if err != nil {
+		return nil, page, err
+	}
+
+	query := `
+	SELECT l.id, l.name, l.thumbnail_image, l.price, l.discount_percentage, l.discount_price, l.average_rating, l.sold, l.is_new, ` + sort.markColumns() + `
+	FROM (` + productListingQuery + `) l` + where
+	query, params = pageLimit(query, sort, cursor, page, params)
+
+	return r.queryProductPage(query, params, sort, cursor, &page)
+}
+
+func (r *homePageRepository) queryProductPage(query string, params []interface{}, sort keysetSort, cursor *pageCursor, page *model.Pagination) ([]*model.Product, model.Pagination, error) {
+	rows, err := r.db.Query(query, params...)
+	if err != nil {
+		r.log.Error("Repository: failed to execute query", zap.Error(err))
+		return nil, *page, err
+	}
+	defer rows.Close()
+
+	r.log.Info("Repository: executed query", zap.String("query", query), zap.Any("params", params))
+
+	var results []*model.Product
+	var marks []keysetMark
+	for rows.Next() {
+		var result model.Product
+		var mark keysetMark
+		if err := rows.Scan(&result.ID, &result.Name, &result.ThumbnailImage, &result.Price, &result.Discount, &result.DiscountPrice,
+			&result.AverageRating, &result.Sold, &result.IsNEW, &mark.key, &mark.id); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, *page, err
+		}
+		results = append(results, &result)
+		marks = append(marks, mark)
+	}
+
+	results = results[:sort.paginate(page, cursor, marks)]
+	if cursor != nil && cursor.Backward {
+		slices.Reverse(results)
+	}
+	return results, *page, nil
+}
+
+func (r *homePageRepository) GetProductFacets(filter model.ProductFilter) (*model.ProductFacets, error) {
+	facets := &model.ProductFacets{Categories: []model.CategoryFacet{}}
+
+	where, params := productFilterClause(filter, facetCategory)
+	categoryQuery := `
+	SELECT l.category_id, l.category_name, COUNT(*)
+	FROM (` + productListingQuery + `) l` + where + `
+	GROUP BY l.category_id, l.category_name
+	ORDER BY COUNT(*) DESC, l.category_name ASC
+	`
+	rows, err := r.db.Query(categoryQuery, params...)
+	if err != nil {
+		r.log.Error("Repository: failed to query category facets", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var facet model.CategoryFacet
+		if err := rows.Scan(&facet.ID, &facet.Name, &facet.Count); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		facets.Categories = append(facets.Categories, facet)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Repository: failed to iterate category facets", zap.Error(err))
+		return nil, err
+	}
+
+	for i := 0; i <= len(priceRangeEdges); i++ {
+		facet := model.PriceRangeFacet{}
+		if i > 0 {
+			facet.Min = priceRangeEdges[i-1]
+		}
+		if i < len(priceRangeEdges) {
+			max := priceRangeEdges[i]
+			facet.Max = &max
+		}
+		facets.PriceRanges = append(facets.PriceRanges, facet)
+	}
+	where, params = productFilterClause(filter, facetPrice)
+	priceQuery := `
+	SELECT width_bucket(l.effective_price, $` + fmt.Sprint(len(params)+1) + `::float8[]) AS bucket, COUNT(*)
+	FROM (` + productListingQuery + `) l` + where + `
+	GROUP BY bucket
+	`
+	rows, err = r.db.Query(priceQuery, append(params, pq.Array(priceRangeEdges))...)
+	if err != nil {
+		r.log.Error("Repository: failed to query price facets", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var bucket, count int
+		if err := rows.Scan(&bucket, &count); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		facets.PriceRanges[bucket].Count = count
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Repository: failed to iterate price facets", zap.Error(err))
+		return nil, err
+	}
+
+	where, params = productFilterClause(filter, facetRating)
+	ratingQuery := `
+	SELECT COUNT(*) FILTER (WHERE l.average_rating >= 4), COUNT(*) FILTER (WHERE l.average_rating >= 3),
+	COUNT(*) FILTER (WHERE l.average_rating >= 2), COUNT(*) FILTER (WHERE l.average_rating >= 1)
+	FROM (` + productListingQuery + `) l` + where
+	facets.Ratings = []model.RatingFacet{{MinRating: 4}, {MinRating: 3}, {MinRating: 2}, {MinRating: 1}}
+	err = r.db.QueryRow(ratingQuery, params...).Scan(&facets.Ratings[0].Count, &facets.Ratings[1].Count, &facets.Ratings[2].Count, &facets.Ratings[3].Count)
+	if err != nil {
+		r.log.Error("Repository: failed to query rating facets", zap.Error(err))
+		return nil, err
+	}
+
+	return facets, nil
+}
+
+// SearchProducts ranks full-text matches first and then products whose name
+// or title only resembles the query, which catches typos. Snippets are built
+// for the requested page only, from HTML-escaped text so that only the <mark>
+// tags added by ts_headline reach the client unescaped.
+func (r *homePageRepository) SearchProducts(query string, categoryID, limit, page int) ([]*model.SearchProduct, int, int, error) {
+	params := []interface{}{query, limit, (page - 1) * limit}
+	categoryFilter := ""
+	if categoryID > 0 {
+		categoryFilter = ` AND p.category_id = $4`
+		params = append(params, categoryID)
+	}
+
+	searchQuery := `
+	WITH q AS (SELECT websearch_to_tsquery('simple', $1) AS query),
+	hits AS (
+		SELECT p.id, p.name, p.images->>0 AS thumbnail_image, p.price, p.created_at,
+		replace(replace(replace(replace(concat_ws(' ', p.title, p.subtitle, p.description),
+			'&', '&amp;'), '<', '&lt;'), '>', '&gt;'), '"', '&quot;') AS document,
+		ts_rank_cd(p.search_vector, q.query) AS relevance,
+		GREATEST(word_similarity($1, p.name), word_similarity($1, p.title)) AS similarity,
+		COUNT(*) OVER () AS total_items
+		FROM products p
+		CROSS JOIN q
+		WHERE p.deleted_at IS NULL
+		  AND (p.search_vector @@ q.query OR $1 <% p.name OR $1 <% p.title)` + categoryFilter + `
+		ORDER BY relevance DESC, similarity DESC, p.id ASC
+		LIMIT $2 OFFSET $3
+	)
+	SELECT h.id, h.name, h.thumbnail_image, h.price,
+	pr.discount_percentage,
+	CASE WHEN pr.discount_percentage = 0 THEN 0 ELSE CAST(pr.unit_price AS FLOAT) END AS discount_price,
+	COALESCE((SELECT AVG(r.rating) FROM ratings r WHERE r.product_id = h.id), 0) AS average_rating,
+	(SELECT COUNT(DISTINCT oi.order_id) FROM order_items oi WHERE oi.product_id = h.id) AS sold,
+	CURRENT_DATE - h.created_at <= INTERVAL '30 days' AS is_new,
+	h.relevance,
+	ts_headline('simple', h.document, q.query, 'StartSel=<mark>, StopSel=</mark>, MaxWords=25, MinWords=10') AS snippet,
+	h.total_items
+	FROM hits h
+	CROSS JOIN q
+	CROSS JOIN LATERAL product_price(h.id) pr
+	ORDER BY h.relevance DESC, h.similarity DESC, h.id ASC
+	`
+
+	rows, err := r.db.Query(searchQuery, params...)
+	if err != nil {
+		r.log.Error("Repository: failed to execute search query", zap.Error(err))
+		return nil, 0, 0, err
+	}
+	defer rows.Close()
+
+	r.log.Info("Repository: executed search query", zap.String("query", query), zap.Int("category_id", categoryID))
+
+	var results []*model.SearchProduct
+	totalItems := 0
+	for rows.Next() {
+		var result model.SearchProduct
+		if err := rows.Scan(&result.ID, &result.Name, &result.ThumbnailImage, &result.Price, &result.Discount, &result.DiscountPrice,
+			&result.AverageRating, &result.Sold, &result.IsNEW, &result.Relevance, &result.Snippet, &totalItems); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, 0, 0, err
+		}
+		results = append(results, &result)
+	}
+	if err := rows.Err(); err != nil {
+		r.log.Error("Repository: failed to iterate search results", zap.Error(err))
+		return nil, 0, 0, err
+	}
+
+	totalPages := (totalItems + limit - 1) / limit
+	return results, totalItems, totalPages, nil
+}
+
+// HasSearchMatch reports whether any product matches query as typed, leaving
+// out the typo fallback, whatever page of results is being shown.
+func (r *homePageRepository) HasSearchMatch(query string, categoryID int) (bool, error) {
+	matchQuery := `
+	SELECT EXISTS (
+		SELECT 1 FROM products p
+		WHERE p.deleted_at IS NULL
+		  AND p.search_vector @@ websearch_to_tsquery('simple', $1)
+		  AND ($2 = 0 OR p.category_id = $2)
+	)
+	`
+	var matched bool
+	if err := r.db.QueryRow(matchQuery, query, categoryID).Scan(&matched); err != nil {
+		r.log.Error("Repository: failed to check search matches", zap.Error(err))
+		return false, err
+	}
+	return matched, nil
+}
+
+// GetSearchSuggestions returns product and category names that resemble
+// query, best match first.
+func (r *homePageRepository) GetSearchSuggestions(query string, limit int) ([]string, error) {
+	suggestionQuery := `
+	SELECT term FROM (
+		SELECT name AS term, word_similarity($1, name) AS score FROM products WHERE deleted_at IS NULL AND $1 <% name
+		UNION
+		SELECT name, word_similarity($1, name) FROM categories WHERE deleted_at IS NULL AND $1 <% name
+	) t
+	WHERE lower(term) <> lower($1)
+	ORDER BY score DESC, term ASC
+	LIMIT $2
+	`
+	rows, err := r.db.Query(suggestionQuery, query, limit)
+	if err != nil {
+		r.log.Error("Repository: failed to query search suggestions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var suggestions []string
+	for rows.Next() {
+		var term string
+		if err := rows.Scan(&term); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		suggestions = append(suggestions, term)
+	}
+
+	return suggestions, nil
+}
+
+// productStockColumn is the sellable stock of product p, resolved the way
+// reserveStock draws from inventories: the variant rows when the product has
+// them, otherwise its product-wide '{}' row.
+const productStockColumn = `COALESCE(
+		(SELECT SUM(i.quantity - i.reserved) FROM inventories i WHERE i.product_id = p.id AND i.variant <> '{}'::jsonb),
+		(SELECT i.quantity - i.reserved FROM inventories i WHERE i.product_id = p.id AND i.variant = '{}'::jsonb),
+		0) AS stock`
+
+func (r *homePageRepository) GetByIdProduct(id int) (*model.ProductID, error) {
+	var product model.ProductID
+	var imagesJSON []byte
+	var variantJSON []byte
+
+	query := `
+	SELECT p.id, p.name, p.images, c.name as category_name, p.price,
+	CASE WHEN c.variant = '{}' THEN NULL ELSE c.variant END AS variant,
+	pr.discount_percentage,
+	CASE WHEN pr.discount_percentage = 0 THEN 0 ELSE CAST(pr.unit_price AS FLOAT) END AS discount_price,
+	COALESCE((SELECT AVG(r.rating) FROM ratings r WHERE r.product_id = p.id), 0) AS average_rating,
+	(SELECT COUNT(DISTINCT oi.order_id) FROM order_items oi WHERE oi.product_id = p.id) AS sold,
+	CURRENT_DATE - p.created_at <= INTERVAL '30 days' AS is_new,
+	` + productStockColumn + `
+	FROM products p
+	JOIN categories c ON p.category_id = c.id
+	CROSS JOIN LATERAL product_price(p.id) pr
+	WHERE p.id = $1 AND p.deleted_at IS NULL
+	`
+	err := r.db.QueryRow(query, id).Scan(&product.ID, &product.Name, &imagesJSON, &product.Category, &product.Price, &variantJSON, &product.Discount, &product.DiscountPrice, &product.AverageRating, &product.Sold, &product.IsNEW, &product.Stock)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, nil
+		}
+		r.log.Error("Repository: failed to scan row", zap.Error(err))
+		return nil, err
+	}
+
+	if len(imagesJSON) > 0 {
+		if err := json.Unmarshal(imagesJSON, &product.Images); err != nil {
+			r.log.Error("Repository: failed to unmarshal images JSON", zap.Error(err))
+			return nil, err
+		}
+	}
+	if len(variantJSON) > 0 {
+		if err := json.Unmarshal(variantJSON, &product.Variant); err != nil {
+			r.log.Error("Repository: failed to unmarshal variant JSON", zap.Error(err))
+			return nil, err
+		}
+	}
+
+	variantStocks, err := r.getVariantStocks(id)
+	if err != nil {
+		return nil, err
+	}
+	product.VariantStocks = variantStocks
+
+	tiers, err := getPriceTiers(context.Background(), r.db, id)
+	if err != nil {
+		r.log.Error("Repository: failed to query price tiers", zap.Error(err))
+		return nil, err
+	}
+	product.PriceTiers = tiers
+
+	return &product, nil
+}
+
+func (r *homePageRepository) getVariantStocks(productID int) ([]model.VariantStock, error) {
+	query := `
+	SELECT variant, quantity - reserved AS stock
+	FROM inventories
+	WHERE product_id = $1 AND variant <> '{}'::jsonb
+	ORDER BY id ASC
+	`
+	rows, err := r.db.Query(query, productID)
+	if err != nil {
+		r.log.Error("Repository: failed to query variant stocks", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []model.VariantStock
+	for rows.Next() {
+		var result model.VariantStock
+		var variantJSON []byte
+		if err := rows.Scan(&variantJSON, &result.Stock); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		if err := json.Unmarshal(variantJSON, &result.Variant); err != nil {
+			r.log.Error("Repository: failed to unmarshal variant JSON", zap.Error(err))
+			return nil, err
+		}
+		results = append(results, result)
+	}
+
+	return results, nil
+}
+
+func (r *homePageRepository) GetAllCategories() ([]*model.Category, error) {
+	rows, err := r.db.Query(`SELECT id, name FROM categories WHERE deleted_at IS NULL ORDER BY id ASC`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var categories []*model.Category
+	for rows.Next() {
+		var category model.Category
+		if err := rows.Scan(&category.ID, &category.Name); err != nil {
+			return nil, err
+		}
+		categories = append(categories, &category)
+	}
+
+	return categories, nil
+}
+
+func (r *homePageRepository) GetAllBanners() ([]*model.BannerWeeklyPromotionRecomment, error) {
+	rows, err := r.db.Query(`SELECT id, image, title, subtitle, path_page FROM banners`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.BannerWeeklyPromotionRecomment
+	for rows.Next() {
+		var result model.BannerWeeklyPromotionRecomment
+		if err := rows.Scan(&result.ID, &result.Image, &result.Title, &result.Subtitle, &result.PathPage); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+
+	return results, nil
+}
+
+// bestSellingSort ranks by orders placed this month. Its name differs from
+// the all-time best_selling listing sort so their cursors are not
+// interchangeable.
+var bestSellingSort = keysetSort{name: "best_selling_month", key: "m.sold", keyType: "bigint", keyDesc: true, id: "l.id"}
+
+func (r *homePageRepository) GetAllBestSellingProducts(page model.Pagination) ([]*model.Product, model.Pagination, error) {
+	from := `
+	FROM (` + productListingQuery + `) l
+	JOIN (
+		SELECT oi.product_id, COUNT(DISTINCT oi.order_id) AS sold
+		FROM order_items oi
+		JOIN orders o ON oi.order_id = o.id
+		WHERE DATE_TRUNC('month', o.created_at) = DATE_TRUNC('month', CURRENT_DATE)
+		GROUP BY oi.product_id
+	) m ON m.product_id = l.id
+	WHERE TRUE`
+
+	cursor, where, params, err := pageWindow(r.db, r.log, bestSellingSort, &page, `SELECT COUNT(*)`+from, "", nil)
+	if err != nil {
+		return nil, page, err
+	}
+
+	query := `
+	SELECT l.id, l.name, l.thumbnail_image, l.price, l.discount_percentage, l.discount_price, l.average_rating, m.sold, l.is_new, ` + bestSellingSort.markColumns() + from + where
+	query, params = pageLimit(query, bestSellingSort, cursor, page, params)
+
+	return r.queryProductPage(query, params, bestSellingSort, cursor, &page)
+}
+
+func (r *homePageRepository) GetAllWeeklyPromotionProducts() ([]*model.BannerWeeklyPromotionRecomment, error) {
+	query := `
+	SELECT p.id, p.images->>0 AS thumbnail_image, p.title, p.subtitle
+	FROM products p
+	CROSS JOIN LATERAL product_price(p.id) pr
+	WHERE pr.promotion_percentage > 0 AND p.deleted_at IS NULL
+	ORDER BY p.id ASC
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.BannerWeeklyPromotionRecomment
+	for rows.Next() {
+		var result model.BannerWeeklyPromotionRecomment
+		if err := rows.Scan(&result.ID, &result.Image, &result.Title, &result.Subtitle); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+
+	return results, nil
+}
+func (r *homePageRepository) GetAllRecommentsProducts() ([]*model.BannerWeeklyPromotionRecomment, error) {
+	query := `
+	SELECT p.id, p.images->>0 AS thumbnail_image, p.title, p.subtitle
+	FROM products p
+	JOIN recomments r ON p.id = r.product_id
+	WHERE p.deleted_at IS NULL
+	ORDER BY p.id ASC;
+	`
+	rows, err := r.db.Query(query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var results []*model.BannerWeeklyPromotionRecomment
+	for rows.Next() {
+		var result model.BannerWeeklyPromotionRecomment
+		if err := rows.Scan(&result.ID, &result.Image, &result.Title, &result.Subtitle); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		results = append(results, &result)
+	}
+
+	return results, nil
+}
+
+func (r *homePageRepository) AddWishlist(wishlist model.Wishlist) error {
+	query := `INSERT INTO wishlists (user_id, product_id) VALUES ($1, $2) RETURNING id`
+	err := r.db.QueryRow(query, wishlist.UserID, wishlist.ProductID).Scan(&wishlist.ID)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return fmt.Errorf("product not found or product already exists in wishlist")
+	}
+
+	r.log.Info("Repository: Wishlist added successfully", zap.Int("id", wishlist.ID))
+	return nil
+}
+
+func (r *homePageRepository) DeleteWishlist(id, userID int) error {
+	query := `DELETE FROM wishlists WHERE id = $1 AND user_id = $2`
+	res, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.log.Warn("Repository: No wishlist found for the given userID and id")
+		return fmt.Errorf("no wishlist found")
+	}
+
+	r.log.Info("Repository: Wishlist deleted successfully", zap.Int("id", id), zap.Int("userID", userID))
+	return nil
+}
+
+func (r *homePageRepository) GetAllWishlist(userID int) ([]*model.WishlistItem, error) {
+	query := `
+	SELECT w.id, p.id, p.name, p.images->>0 AS thumbnail_image, p.price,
+	pr.discount_percentage,
+	CASE WHEN pr.discount_percentage = 0 THEN 0 ELSE CAST(pr.unit_price AS FLOAT) END AS discount_price,
+	COALESCE((SELECT AVG(r.rating) FROM ratings r WHERE r.product_id = p.id), 0) AS average_rating,
+	(SELECT COUNT(DISTINCT oi.order_id) FROM order_items oi WHERE oi.product_id = p.id) AS sold,
+	CURRENT_DATE - p.created_at <= INTERVAL '30 days' AS is_new,
+	` + productStockColumn + `
+	FROM wishlists w
+	JOIN products p ON w.product_id = p.id
+	CROSS JOIN LATERAL product_price(p.id) pr
+	WHERE w.user_id = $1 AND p.deleted_at IS NULL
+	ORDER BY w.id DESC
+	`
+	rows, err := r.db.Query(query, userID)
+	if err != nil {
+		r.log.Error("Repository: failed to execute query", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*model.WishlistItem{}
+	for rows.Next() {
+		var result model.WishlistItem
+		if err := rows.Scan(&result.WishlistID, &result.ID, &result.Name, &result.ThumbnailImage, &result.Price, &result.Discount, &result.DiscountPrice,
+			&result.AverageRating, &result.Sold, &result.IsNEW, &result.Stock); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		result.Available = result.Stock > 0
+		results = append(results, &result)
+	}
+
+	return results, nil
+}
+
+func (r *homePageRepository) CreateWishlistShare(userID int, token string) (string, error) {
+	query := `
+	INSERT INTO wishlist_shares (user_id, token) VALUES ($1, $2)
+	ON CONFLICT (user_id) DO UPDATE SET token = wishlist_shares.token
+	RETURNING token
+	`
+	var result string
+	if err := r.db.QueryRow(query, userID, token).Scan(&result); err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return "", err
+	}
+
+	r.log.Info("Repository: Wishlist share created", zap.Int("userID", userID))
+	return result, nil
+}
+
+func (r *homePageRepository) DeleteWishlistShare(userID int) error {
+	res, err := r.db.Exec(`DELETE FROM wishlist_shares WHERE user_id = $1`, userID)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.log.Warn("Repository: No wishlist share found for the given userID", zap.Int("userID", userID))
+		return fmt.Errorf("no wishlist share found")
+	}
+
+	r.log.Info("Repository: Wishlist share deleted", zap.Int("userID", userID))
+	return nil
+}
+
+func (r *homePageRepository) GetSharedWishlist(token string) (*model.SharedWishlist, error) {
+	var userID int
+	var result model.SharedWishlist
+	query := `
+	SELECT u.id, u.name
+	FROM wishlist_shares ws
+	JOIN users u ON ws.user_id = u.id
+	WHERE ws.token = $1
+	`
+	err := r.db.QueryRow(query, token).Scan(&userID, &result.OwnerName)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: No wishlist share found for the given token")
+			return nil, fmt.Errorf("no wishlist share found")
+		}
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return nil, err
+	}
+
+	items, err := r.GetAllWishlist(userID)
+	if err != nil {
+		return nil, err
+	}
+	result.Items = items
+
+	return &result, nil
+}
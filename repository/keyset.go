@@ -0,0 +1,162 @@
+package repository
+
+import (
+	"database/sql"
+	"ecommerce/model"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"slices"
+
+	"go.uber.org/zap"
+)
+
+var ErrInvalidCursor = errors.New("invalid cursor")
+
+// pageCursor is the decoded form of the opaque next_cursor/prev_cursor. It
+// holds the sort key and id of the row to continue from, as text so any key
+// type round-trips exactly through Postgres.
+type pageCursor struct {
+	Sort     string `json:"s"`
+	Key      string `json:"k,omitempty"`
+	ID       int    `json:"i"`
+	Backward bool   `json:"b,omitempty"`
+}
+
+// keysetMark is the sort key and id of a fetched row.
+type keysetMark struct {
+	key string
+	id  int
+}
+
+// keysetSort orders by an optional key and then by a unique id, which lets a
+// cursor resume exactly after a row even while rows are being inserted.
+type keysetSort struct {
+	name    string
+	key     string
+	keyType string
+	keyDesc bool
+	id      string
+	idDesc  bool
+}
+
+func (s keysetSort) decodeCursor(value string) (*pageCursor, error) {
+	data, err := base64.RawURLEncoding.DecodeString(value)
+	if err != nil {
+		return nil, ErrInvalidCursor
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != s.name {
+		return nil, ErrInvalidCursor
+	}
+	return &c, nil
+}
+
+func (s keysetSort) encodeCursor(mark keysetMark, backward bool) string {
+	data, _ := json.Marshal(pageCursor{Sort: s.name, Key: mark.key, ID: mark.id, Backward: backward})
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// orderBy returns the ORDER BY list, reversed when fetching backward.
+func (s keysetSort) orderBy(backward bool) string {
+	order := func(expr string, desc bool) string {
+		if desc != backward {
+			return expr + " DESC"
+		}
+		return expr + " ASC"
+	}
+	if s.key == "" {
+		return order(s.id, s.idDesc)
+	}
+	return order(s.key, s.keyDesc) + ", " + order(s.id, s.idDesc)
+}
+
+// markColumns is the select-list entry that carries a row's keysetMark.
+func (s keysetSort) markColumns() string {
+	if s.key == "" {
+		return `'', ` + s.id
+	}
+	return `CAST(` + s.key + ` AS text), ` + s.id
+}
+
+// seek returns the condition for rows past c in its direction, numbering its
+// placeholders from next.
+func (s keysetSort) seek(c *pageCursor, next int) (string, []interface{}) {
+	op := func(desc bool) string {
+		if desc != c.Backward {
+			return "<"
+		}
+		return ">"
+	}
+	idParam := fmt.Sprintf("$%d", next)
+	if s.key == "" {
+		return fmt.Sprintf(`%s %s %s`, s.id, op(s.idDesc), idParam), []interface{}{c.ID}
+	}
+	keyParam := fmt.Sprintf("CAST($%d AS %s)", next+1, s.keyType)
+	condition := fmt.Sprintf(`(%s %s %s OR (%s = %s AND %s %s %s))`,
+		s.key, op(s.keyDesc), keyParam, s.key, keyParam, s.id, op(s.idDesc), idParam)
+	return condition, []interface{}{c.ID, c.Key}
+}
+
+// paginate finishes a page and fills its cursors. In cursor mode one row
+// past the limit is fetched to tell whether another page follows; it is
+// dropped here. It returns how many fetched rows belong to the page; when c
+// is backward the caller must also reverse them into display order.
+func (s keysetSort) paginate(p *model.Pagination, c *pageCursor, marks []keysetMark) int {
+	hasNext, hasPrev := p.Page < p.TotalPages, p.Page > 1
+	if c != nil {
+		more := len(marks) > p.Limit
+		if more {
+			marks = marks[:p.Limit]
+		}
+		if c.Backward {
+			slices.Reverse(marks)
+			hasNext, hasPrev = true, more
+		} else {
+			hasNext, hasPrev = more, true
+		}
+	}
+
+	if len(marks) > 0 {
+		if hasNext {
+			p.NextCursor = s.encodeCursor(marks[len(marks)-1], false)
+		}
+		if hasPrev {
+			p.PrevCursor = s.encodeCursor(marks[0], true)
+		}
+	}
+	return len(marks)
+}
+
+// pageWindow prepares a listing query for the requested page. With a cursor
+// it adds the seek condition to where; without one it runs countQuery to
+// fill the totals for offset paging.
+func pageWindow(db *sql.DB, log *zap.Logger, sort keysetSort, page *model.Pagination, countQuery, where string, params []interface{}) (*pageCursor, string, []interface{}, error) {
+	if page.Cursor != "" {
+		cursor, err := sort.decodeCursor(page.Cursor)
+		if err != nil {
+			return nil, where, params, err
+		}
+		condition, seekParams := sort.seek(cursor, len(params)+1)
+		return cursor, where + ` AND ` + condition, append(params, seekParams...), nil
+	}
+
+	if err := db.QueryRow(countQuery, params...).Scan(&page.TotalItems); err != nil {
+		log.Error("Repository: failed to execute count query", zap.Error(err))
+		return nil, where, params, err
+	}
+	page.TotalPages = (page.TotalItems + page.Limit - 1) / page.Limit
+	return nil, where, params, nil
+}
+
+// pageLimit appends ORDER BY and either the cursor LIMIT, which fetches one
+// extra row to detect a following page, or LIMIT/OFFSET.
+func pageLimit(query string, sort keysetSort, cursor *pageCursor, page model.Pagination, params []interface{}) (string, []interface{}) {
+	query += ` ORDER BY ` + sort.orderBy(cursor != nil && cursor.Backward)
+	if cursor != nil {
+		return query + ` LIMIT $` + fmt.Sprint(len(params)+1), append(params, page.Limit+1)
+	}
+	query += ` LIMIT $` + fmt.Sprint(len(params)+1) + ` OFFSET $` + fmt.Sprint(len(params)+2)
+	return query, append(params, page.Limit, (page.Page-1)*page.Limit)
+}
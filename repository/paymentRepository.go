@@ -0,0 +1,168 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ecommerce/model"
+	"errors"
+	"fmt"
+
+	"github.com/lib/pq"
+	"go.uber.org/zap"
+)
+
+var (
+	ErrDuplicatePaymentEvent = errors.New("payment event already processed")
+	ErrPaymentStatusChanged  = errors.New("payment status has changed")
+)
+
+type PaymentRepository interface {
+	CreatePayment(payment *model.Payment) error
+	GetLatestPayment(orderID int) (*model.Payment, error)
+	GetPaymentByRef(provider, providerRef string) (*model.Payment, error)
+	PaymentEventExists(provider, eventID string) (bool, error)
+	ApplyPaymentEvent(payment *model.Payment, eventID, eventType string, fromStatuses []string, paymentStatus, fromOrderStatus, toOrderStatus string, changedBy int) error
+}
+
+type paymentRepository struct {
+	db     *sql.DB
+	log    *zap.Logger
+	orders CheckoutRepository
+}
+
+func NewPaymentRepository(db *sql.DB, logger *zap.Logger, orders CheckoutRepository) PaymentRepository {
+	return &paymentRepository{db: db, log: logger, orders: orders}
+}
+
+const paymentColumns = `id, order_id, provider, provider_ref, amount, status, created_at, updated_at`
+
+func scanPayment(row *sql.Row) (*model.Payment, error) {
+	var payment model.Payment
+	err := row.Scan(&payment.ID, &payment.OrderID, &payment.Provider, &payment.ProviderRef, &payment.Amount, &payment.Status, &payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		return nil, err
+	}
+	return &payment, nil
+}
+
+func (r *paymentRepository) CreatePayment(payment *model.Payment) error {
+	query := `
+        INSERT INTO payments (order_id, provider, provider_ref, amount)
+        VALUES ($1, $2, $3, $4)
+        RETURNING id, status, created_at, updated_at
+    `
+	err := r.db.QueryRow(query, payment.OrderID, payment.Provider, payment.ProviderRef, payment.Amount).
+		Scan(&payment.ID, &payment.Status, &payment.CreatedAt, &payment.UpdatedAt)
+	if err != nil {
+		r.log.Error("Repository: failed to create payment", zap.Error(err))
+		return fmt.Errorf("failed to create payment: %w", err)
+	}
+
+	r.log.Info("Repository: payment created", zap.Int("id", payment.ID), zap.Int("orderID", payment.OrderID), zap.String("provider", payment.Provider))
+	return nil
+}
+
+func (r *paymentRepository) GetLatestPayment(orderID int) (*model.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE order_id = $1 ORDER BY created_at DESC, id DESC LIMIT 1`
+	payment, err := scanPayment(r.db.QueryRow(query, orderID))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			return nil, fmt.Errorf("payment not found")
+		}
+		r.log.Error("Repository: failed to query payment", zap.Error(err))
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (r *paymentRepository) GetPaymentByRef(provider, providerRef string) (*model.Payment, error) {
+	query := `SELECT ` + paymentColumns + ` FROM payments WHERE provider = $1 AND provider_ref = $2`
+	payment, err := scanPayment(r.db.QueryRow(query, provider, providerRef))
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: No payment found for provider reference", zap.String("provider", provider), zap.String("providerRef", providerRef))
+			return nil, fmt.Errorf("payment not found")
+		}
+		r.log.Error("Repository: failed to query payment", zap.Error(err))
+		return nil, err
+	}
+
+	return payment, nil
+}
+
+func (r *paymentRepository) PaymentEventExists(provider, eventID string) (bool, error) {
+	query := `SELECT EXISTS (SELECT 1 FROM payment_events WHERE provider = $1 AND event_id = $2)`
+	var exists bool
+	if err := r.db.QueryRow(query, provider, eventID).Scan(&exists); err != nil {
+		r.log.Error("Repository: failed to query payment event", zap.Error(err))
+		return false, fmt.Errorf("failed to query payment event: %w", err)
+	}
+	return exists, nil
+}
+
+// ApplyPaymentEvent records the event, updates the payment and, when
+// toOrderStatus is set, moves the order in one transaction so a webhook that
+// fails half way can be retried safely. An empty eventID skips the
+// idempotency check for changes that did not come from a webhook. The
+// payment only moves while its status is one of fromStatuses; otherwise
+// ErrPaymentStatusChanged is returned and nothing is recorded.
+func (r *paymentRepository) ApplyPaymentEvent(payment *model.Payment, eventID, eventType string, fromStatuses []string, paymentStatus, fromOrderStatus, toOrderStatus string, changedBy int) error {
+	ctx := context.Background()
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	if eventID != "" {
+		eventQuery := `
+            INSERT INTO payment_events (provider, event_id, payment_id, type)
+            VALUES ($1, $2, $3, $4)
+            ON CONFLICT (provider, event_id) DO NOTHING
+            RETURNING id
+        `
+		var id int
+		err = tx.QueryRowContext(ctx, eventQuery, payment.Provider, eventID, payment.ID, eventType).Scan(&id)
+		if err != nil {
+			tx.Rollback()
+			if errors.Is(err, sql.ErrNoRows) {
+				r.log.Info("Repository: payment event already processed", zap.String("eventID", eventID))
+				return ErrDuplicatePaymentEvent
+			}
+			r.log.Error("Repository: failed to record payment event", zap.Error(err))
+			return fmt.Errorf("failed to record payment event: %w", err)
+		}
+	}
+
+	updateQuery := `
+        UPDATE payments SET status = $2, updated_at = NOW()
+        WHERE id = $1 AND status = ANY($3)
+        RETURNING updated_at
+    `
+	err = tx.QueryRowContext(ctx, updateQuery, payment.ID, paymentStatus, pq.Array(fromStatuses)).Scan(&payment.UpdatedAt)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: payment status changed concurrently", zap.Int("paymentID", payment.ID), zap.String("to", paymentStatus))
+			return ErrPaymentStatusChanged
+		}
+		r.log.Error("Repository: failed to update payment status", zap.Error(err))
+		return fmt.Errorf("failed to update payment status: %w", err)
+	}
+	payment.Status = paymentStatus
+
+	if toOrderStatus != "" {
+		note := fmt.Sprintf("payment %s %s", payment.ProviderRef, paymentStatus)
+		if err := r.orders.updateOrderStatus(ctx, tx, payment.OrderID, fromOrderStatus, toOrderStatus, changedBy, note); err != nil {
+			tx.Rollback()
+			return err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.log.Info("Repository: payment event applied", zap.Int("paymentID", payment.ID), zap.String("type", eventType), zap.String("status", paymentStatus), zap.String("orderStatus", toOrderStatus))
+	return nil
+}
@@ -0,0 +1,106 @@
+package repository
+
+import (
+	"context"
+	"database/sql"
+	"ecommerce/model"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// PricingRepository reads prices from product_price(), the database function
+// every listing, cart line and order is priced with.
+type PricingRepository interface {
+	GetPrice(productID, quantity int, at time.Time) (*model.PriceQuote, error)
+	PreviewCoupon(userID int, code string, productID int, subtotal float64, at time.Time) (*model.CouponPreview, error)
+}
+
+type pricingRepository struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewPricingRepository(db *sql.DB, logger *zap.Logger) PricingRepository {
+	return &pricingRepository{db: db, log: logger}
+}
+
+func (r *pricingRepository) GetPrice(productID, quantity int, at time.Time) (*model.PriceQuote, error) {
+	query := `
+	SELECT pr.base_price, pr.promotion_percentage, pr.tier_percentage, pr.discount_percentage, pr.unit_price
+	FROM products p
+	CROSS JOIN LATERAL product_price(p.id, $2, $3) pr
+	WHERE p.id = $1 AND p.deleted_at IS NULL
+	`
+	quote := model.PriceQuote{ProductID: productID, Quantity: quantity, At: at}
+	err := r.db.QueryRow(query, productID, quantity, at).Scan(&quote.BasePrice, &quote.PromotionPercentage, &quote.TierPercentage,
+		&quote.DiscountPercentage, &quote.UnitPrice)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: Product not found", zap.Int("id", productID))
+			return nil, ErrProductNotFound
+		}
+		r.log.Error("Repository: failed to query price", zap.Error(err))
+		return nil, err
+	}
+	return &quote, nil
+}
+
+// PreviewCoupon prices code against a single line of productID worth
+// subtotal, with the same rules checkout applies to the cart.
+func (r *pricingRepository) PreviewCoupon(userID int, code string, productID int, subtotal float64, at time.Time) (*model.CouponPreview, error) {
+	ctx := context.Background()
+	coupon, used, usedByUser, err := loadCoupon(ctx, r.db, r.log, userID, code, false)
+	if err != nil {
+		return nil, err
+	}
+
+	line := couponLine{productID: productID, total: subtotal}
+	err = r.db.QueryRowContext(ctx, `SELECT COALESCE(category_id, 0) FROM products WHERE id = $1`, productID).Scan(&line.categoryID)
+	if err != nil {
+		r.log.Error("Repository: failed to query product category", zap.Error(err))
+		return nil, err
+	}
+
+	return priceCoupon(coupon, []couponLine{line}, used, usedByUser, at)
+}
+
+// getPriceTiers returns a product's bulk tiers, smallest quantity first.
+func getPriceTiers(ctx context.Context, q contextQuerier, productID int) ([]model.PriceTier, error) {
+	rows, err := q.QueryContext(ctx, `SELECT min_quantity, discount_percentage FROM product_price_tiers WHERE product_id = $1 ORDER BY min_quantity ASC`, productID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var tiers []model.PriceTier
+	for rows.Next() {
+		var tier model.PriceTier
+		if err := rows.Scan(&tier.MinQuantity, &tier.DiscountPercentage); err != nil {
+			return nil, err
+		}
+		tiers = append(tiers, tier)
+	}
+	return tiers, rows.Err()
+}
+
+type contextExecer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+}
+
+// replacePriceTiers swaps a product's bulk tiers for tiers.
+func replacePriceTiers(ctx context.Context, tx contextExecer, productID int, tiers []model.PriceTier) error {
+	if _, err := tx.ExecContext(ctx, `DELETE FROM product_price_tiers WHERE product_id = $1`, productID); err != nil {
+		return fmt.Errorf("failed to clear price tiers: %w", err)
+	}
+	for _, tier := range tiers {
+		_, err := tx.ExecContext(ctx, `INSERT INTO product_price_tiers (product_id, min_quantity, discount_percentage) VALUES ($1, $2, $3)`,
+			productID, tier.MinQuantity, tier.DiscountPercentage)
+		if err != nil {
+			return fmt.Errorf("failed to save price tier: %w", err)
+		}
+	}
+	return nil
+}
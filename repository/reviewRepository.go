@@ -0,0 +1,188 @@
+package repository
+
+import (
+	"database/sql"
+	"ecommerce/model"
+	"errors"
+	"fmt"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrReviewNotAllowed = errors.New("product can only be reviewed from a delivered order")
+	ErrReviewExists     = errors.New("product already reviewed for this order")
+)
+
+type ReviewRepository interface {
+	CreateReview(review *model.Review) error
+	GetReviewsByProduct(productID, limit, page int) (*model.ProductReviews, int, int, error)
+	UpdateReview(review model.Review) (*model.Review, error)
+	DeleteReview(id, userID int) error
+}
+
+type reviewRepository struct {
+	db  *sql.DB
+	log *zap.Logger
+}
+
+func NewReviewRepository(db *sql.DB, logger *zap.Logger) ReviewRepository {
+	return &reviewRepository{db: db, log: logger}
+}
+
+func (r *reviewRepository) CreateReview(review *model.Review) error {
+	query := `
+	INSERT INTO ratings (order_id, product_id, user_id, rating, review)
+	SELECT o.id, oi.product_id, o.user_id, $3, NULLIF($4, '')
+	FROM orders o
+	JOIN order_items oi ON oi.order_id = o.id
+	WHERE o.user_id = $1
+	  AND oi.product_id = $2
+	  AND o.status = 'delivered'
+	  AND ($5 = 0 OR o.id = $5)
+	  AND NOT EXISTS (SELECT 1 FROM ratings r WHERE r.order_id = o.id AND r.product_id = oi.product_id)
+	ORDER BY o.created_at DESC, o.id DESC
+	LIMIT 1
+	ON CONFLICT (order_id, product_id) DO NOTHING
+	RETURNING id, order_id, created_at, updated_at
+	`
+	err := r.db.QueryRow(query, review.UserID, review.ProductID, review.Rating, review.Review, review.OrderID).
+		Scan(&review.ID, &review.OrderID, &review.CreatedAt, &review.UpdatedAt)
+	if err == nil {
+		r.log.Info("Repository: review created successfully", zap.Int("id", review.ID))
+		return nil
+	}
+	if !errors.Is(err, sql.ErrNoRows) {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	var delivered bool
+	checkQuery := `
+	SELECT EXISTS (
+		SELECT 1
+		FROM orders o
+		JOIN order_items oi ON oi.order_id = o.id
+		WHERE o.user_id = $1 AND oi.product_id = $2 AND o.status = 'delivered' AND ($3 = 0 OR o.id = $3)
+	)
+	`
+	if err := r.db.QueryRow(checkQuery, review.UserID, review.ProductID, review.OrderID).Scan(&delivered); err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+	if delivered {
+		r.log.Warn("Repository: review already exists", zap.Int("userID", review.UserID), zap.Int("productID", review.ProductID))
+		return ErrReviewExists
+	}
+
+	r.log.Warn("Repository: no delivered order for review", zap.Int("userID", review.UserID), zap.Int("productID", review.ProductID))
+	return ErrReviewNotAllowed
+}
+
+func (r *reviewRepository) GetReviewsByProduct(productID, limit, page int) (*model.ProductReviews, int, int, error) {
+	summaryQuery := `
+	SELECT rating, COUNT(*)
+	FROM ratings
+	WHERE product_id = $1
+	GROUP BY rating
+	`
+	rows, err := r.db.Query(summaryQuery, productID)
+	if err != nil {
+		r.log.Error("Repository: failed to execute summary query", zap.Error(err))
+		return nil, 0, 0, err
+	}
+
+	result := &model.ProductReviews{
+		Summary: model.ReviewSummary{Histogram: map[int]int{1: 0, 2: 0, 3: 0, 4: 0, 5: 0}},
+		Reviews: []*model.Review{},
+	}
+	var totalRating int
+	for rows.Next() {
+		var rating, count int
+		if err := rows.Scan(&rating, &count); err != nil {
+			rows.Close()
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, 0, 0, err
+		}
+		result.Summary.Histogram[rating] = count
+		result.Summary.TotalReviews += count
+		totalRating += rating * count
+	}
+	rows.Close()
+
+	if result.Summary.TotalReviews > 0 {
+		result.Summary.AverageRating = float64(totalRating) / float64(result.Summary.TotalReviews)
+	}
+
+	totalItems := result.Summary.TotalReviews
+	totalPages := (totalItems + limit - 1) / limit
+	offset := (page - 1) * limit
+
+	query := `
+	SELECT r.id, COALESCE(r.order_id, 0), r.product_id, r.user_id, u.name, r.rating, COALESCE(r.review, ''), r.created_at, r.updated_at
+	FROM ratings r
+	JOIN users u ON r.user_id = u.id
+	WHERE r.product_id = $1
+	ORDER BY r.created_at DESC, r.id DESC
+	LIMIT $2 OFFSET $3
+	`
+	rows, err = r.db.Query(query, productID, limit, offset)
+	if err != nil {
+		r.log.Error("Repository: failed to execute query", zap.Error(err))
+		return nil, 0, 0, err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var review model.Review
+		if err := rows.Scan(&review.ID, &review.OrderID, &review.ProductID, &review.UserID, &review.UserName, &review.Rating, &review.Review,
+			&review.CreatedAt, &review.UpdatedAt); err != nil {
+			r.log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, 0, 0, err
+		}
+		result.Reviews = append(result.Reviews, &review)
+	}
+
+	return result, totalItems, totalPages, nil
+}
+
+func (r *reviewRepository) UpdateReview(review model.Review) (*model.Review, error) {
+	query := `
+	UPDATE ratings
+	SET rating = $3, review = NULLIF($4, ''), updated_at = NOW()
+	WHERE id = $1 AND user_id = $2
+	RETURNING id, COALESCE(order_id, 0), product_id, rating, COALESCE(review, ''), created_at, updated_at
+	`
+	var result model.Review
+	err := r.db.QueryRow(query, review.ID, review.UserID, review.Rating, review.Review).
+		Scan(&result.ID, &result.OrderID, &result.ProductID, &result.Rating, &result.Review, &result.CreatedAt, &result.UpdatedAt)
+	if err != nil {
+		if errors.Is(err, sql.ErrNoRows) {
+			r.log.Warn("Repository: No review found for the given userID and id", zap.Int("id", review.ID), zap.Int("userID", review.UserID))
+			return nil, fmt.Errorf("review not found")
+		}
+		r.log.Error("Repository: Error updating review", zap.Error(err))
+		return nil, err
+	}
+
+	r.log.Info("Repository: review updated successfully", zap.Int("id", result.ID))
+	return &result, nil
+}
+
+func (r *reviewRepository) DeleteReview(id, userID int) error {
+	query := `DELETE FROM ratings WHERE id = $1 AND user_id = $2`
+	res, err := r.db.Exec(query, id, userID)
+	if err != nil {
+		r.log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.log.Warn("Repository: No review found for the given userID and id")
+		return fmt.Errorf("review not found")
+	}
+
+	r.log.Info("Repository: Review deleted successfully", zap.Int("id", id), zap.Int("userID", userID))
+	return nil
+}
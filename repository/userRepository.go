@@ -1,354 +1,927 @@
-package repository
-
-import (
-	"database/sql"
-	"ecommerce/model"
-	"encoding/json"
-	"fmt"
-
-	"go.uber.org/zap"
-)
-
-type AuthRepository interface {
-	Create(user *model.User) error
-	GetUserLogin(user model.User) (*model.User, error)
-	CreateSession(session *model.Session) error
-	GetSessionByToken(token string) (*model.Session, error)
-	DeleteSession(token string) error
-	GetAllAddress(id int) ([]*model.User, error)
-	GetDetailUser(id int) (*model.User, error)
-	UpdateUser(userID int, name, email, oldPassword, newPassword string) (*model.User, error)
-	UpdateAddressUser(userID int, address []string) (*model.User, error)
-	SetDefaultAddress(userID int, addressIndex int) (*model.User, error)
-	DeleteAddress(userID int, addressIndex int) (*model.User, error)
-	CreateAddress(userID int, newAddress string) (*model.User, error)
-}
-
-type authRepository struct {
-	DB  *sql.DB
-	Log *zap.Logger
-}
-
-func NewAuthRepository(db *sql.DB, logger *zap.Logger) AuthRepository {
-	return &authRepository{DB: db, Log: logger}
-}
-
-func (r *authRepository) Create(user *model.User) error {
-	var query string
-	var err error
-
-	if user.Email != "" {
-		query = `INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING id`
-		r.Log.Info("Repository: Executing query", zap.String("query", query), zap.Any("params", []interface{}{user.Name, user.Email, user.Password}))
-		err = r.DB.QueryRow(query, user.Name, user.Email, user.Password).Scan(&user.ID)
-	} else if user.Phone != "" {
-		query = `INSERT INTO users (name, phone, password) VALUES ($1, $2, $3) RETURNING id`
-		r.Log.Info("Repository: Executing query", zap.String("query", query), zap.Any("params", []interface{}{user.Name, user.Phone, user.Password}))
-		err = r.DB.QueryRow(query, user.Name, user.Phone, user.Password).Scan(&user.ID)
-	} else {
-		r.Log.Error("Repository: Validation failed", zap.String("reason", "either email or phone must be provided"))
-		return fmt.Errorf("either email or phone must be provided")
-	}
-
-	if err != nil {
-		r.Log.Error("Repository: Error executing query", zap.Error(err))
-		return err
-	}
-
-	r.Log.Info("Repository: User created successfully", zap.Int("userID", user.ID))
-	return nil
-
-}
-
-func (r *authRepository) GetUserLogin(user model.User) (*model.User, error) {
-	query := `SELECT id, name, email, phone, password FROM users WHERE (email = $1 OR phone = $2) AND password = $3`
-	r.Log.Info("Repository: Executing query", zap.String("query", query), zap.Any("params", []interface{}{user.Email, user.Phone, user.Password}))
-
-	var userResponse model.User
-	var email sql.NullString
-	var phone sql.NullString
-	err := r.DB.QueryRow(query, user.Email, user.Phone, user.Password).Scan(&userResponse.ID, &userResponse.Name, &email, &phone, &user.Password)
-
-	if err != nil {
-		if err == sql.ErrNoRows {
-			r.Log.Warn("Repository: no user found with the provided credentials")
-			return nil, fmt.Errorf("invalid username or password")
-		}
-
-		r.Log.Error("Repository: Error executing query", zap.Error(err))
-		return nil, err
-	}
-
-	r.Log.Info("Repository: User login successful", zap.Int("userID", userResponse.ID))
-
-	return &userResponse, nil
-}
-
-func (r *authRepository) CreateSession(session *model.Session) error {
-	query := "INSERT INTO sessions (user_id, token, expires_at) VALUES ($1, $2, $3)"
-	_, err := r.DB.Exec(query, session.UserID, session.Token, session.ExpiresAt)
-	if err != nil {
-		return nil
-	}
-	return nil
-}
-
-func (r *authRepository) GetSessionByToken(token string) (*model.Session, error) {
-	var session model.Session
-	query := "SELECT user_id, token, expires_at FROM sessions WHERE token=$1"
-	err := r.DB.QueryRow(query, token).Scan(&session.UserID, &session.Token, &session.ExpiresAt)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, fmt.Errorf("session not found or expired")
-		}
-		return nil, err
-	}
-	return &session, nil
-}
-
-func (r *authRepository) DeleteSession(token string) error {
-	query := "DELETE FROM sessions WHERE token=$1"
-	res, err := r.DB.Exec(query, token)
-	if err != nil {
-		fmt.Println("Error executing delete:", err)
-		return err
-	}
-
-	rowsAffected, _ := res.RowsAffected()
-	fmt.Println("Rows affected by delete:", rowsAffected)
-	if rowsAffected == 0 {
-		fmt.Println("No session found with this token.")
-	}
-
-	return nil
-}
-
-func (r *authRepository) GetAllAddress(id int) ([]*model.User, error) {
-	var addressJSON []byte
-	rows, err := r.DB.Query(`SELECT id, address FROM users WHERE id = $1`, id)
-	if err != nil {
-		return nil, err
-	}
-	defer rows.Close()
-
-	var results []*model.User
-	for rows.Next() {
-		var result model.User
-		if err := rows.Scan(&result.ID, &addressJSON); err != nil {
-			r.Log.Error("Repository: failed to scan row", zap.Error(err))
-			return nil, err
-		}
-		if len(addressJSON) > 0 {
-			if err := json.Unmarshal(addressJSON, &result.Address); err != nil {
-				r.Log.Error("Repository: failed to unmarshal images JSON", zap.Error(err))
-				return nil, err
-			}
-		}
-		results = append(results, &result)
-	}
-
-	return results, nil
-}
-func (r *authRepository) GetDetailUser(id int) (*model.User, error) {
-	var addressJSON []byte
-	var user model.User
-
-	err := r.DB.QueryRow(`SELECT name, email, phone, address FROM users WHERE id = $1`, id).
-		Scan(&user.Name, &user.Email, &user.Phone, &addressJSON)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			r.Log.Warn("Repository: User not found", zap.Int("id", id))
-			return nil, fmt.Errorf("user with id %d not found", id)
-		}
-		r.Log.Error("Repository: Failed to query user", zap.Error(err))
-		return nil, err
-	}
-	r.Log.Info("Repository: Executing query", zap.Int("user_id", id))
-
-	if len(addressJSON) > 0 {
-		if err := json.Unmarshal(addressJSON, &user.Address); err != nil {
-			r.Log.Error("Repository: Failed to unmarshal address JSON", zap.String("address_json", string(addressJSON)), zap.Error(err))
-			return nil, fmt.Errorf("invalid address format for user id %d: %w", id, err)
-		}
-	}
-
-	r.Log.Info("Repository: Retrieved user details", zap.Any("user", user))
-	return &user, nil
-}
-
-func (r *authRepository) UpdateUser(userID int, name, email, oldPassword, newPassword string) (*model.User, error) {
-	r.Log.Info("Repository: Updating user details", zap.Int("userID", userID), zap.String("name", name), zap.String("email", email))
-
-	var storedPassword string
-	err := r.DB.QueryRow(`SELECT password FROM users WHERE id = $1`, userID).Scan(&storedPassword)
-	if err != nil {
-		if err == sql.ErrNoRows {
-			r.Log.Error("Repository: User not found", zap.Int("userID", userID))
-			return nil, fmt.Errorf("user not found")
-		}
-		r.Log.Error("Repository: Failed to fetch user password", zap.Error(err))
-		return nil, fmt.Errorf("failed to fetch user password: %w", err)
-	}
-
-	if oldPassword != storedPassword {
-		r.Log.Error("Repository: Incorrect old password", zap.Int("userID", userID))
-		return nil, fmt.Errorf("incorrect old password")
-	}
-
-	query := `
-        UPDATE users
-        SET name = $1, email = $2, password = $3, updated_at = NOW()
-        WHERE id = $4
-        RETURNING id, name, email, password
-    `
-
-	var updatedUser model.User
-	err = r.DB.QueryRow(query, name, email, newPassword, userID).Scan(
-		&updatedUser.ID,
-		&updatedUser.Name,
-		&updatedUser.Email,
-		&updatedUser.Password,
-	)
-	if err != nil {
-		r.Log.Error("Repository: Failed to update user", zap.Error(err))
-		return nil, fmt.Errorf("failed to update user: %w", err)
-	}
-
-	r.Log.Info("Repository: User details updated successfully", zap.Int("userID", updatedUser.ID))
-	return &updatedUser, nil
-}
-
-func (r *authRepository) UpdateAddressUser(userID int, address []string) (*model.User, error) {
-	r.Log.Info("Repository: Updating user details", zap.Int("userID", userID), zap.Any("address", address))
-
-	addressJSON, err := json.Marshal(address)
-	if err != nil {
-		r.Log.Error("Repository: Failed to marshal address JSON", zap.Error(err))
-		return nil, fmt.Errorf("failed to serialize address: %w", err)
-	}
-
-	query := `
-        UPDATE users
-        SET address = $1, updated_at = NOW()
-        WHERE id = $2
-        RETURNING id, address
-    `
-
-	var updatedUser model.User
-	err = r.DB.QueryRow(query, addressJSON, userID).Scan(
-		&updatedUser.ID,
-		&addressJSON,
-	)
-	if err != nil {
-		r.Log.Error("Repository: Failed to update user", zap.Error(err))
-		return nil, fmt.Errorf("failed to update user: %w", err)
-	}
-
-	if err := json.Unmarshal(addressJSON, &updatedUser.Address); err != nil {
-		r.Log.Error("Repository: Failed to unmarshal address JSON", zap.Error(err))
-		return nil, fmt.Errorf("failed to deserialize address: %w", err)
-	}
-
-	r.Log.Info("Repository: User details updated successfully", zap.Int("userID", updatedUser.ID))
-	return &updatedUser, nil
-}
-
-func (r *authRepository) SetDefaultAddress(userID int, addressIndex int) (*model.User, error) {
-	var defaultAddress string
-
-	addressQuery := `
-        SELECT COALESCE(address->>$2, address->>0) AS default_address
-        FROM users 
-        WHERE id = $1
-    `
-
-	err := r.DB.QueryRow(addressQuery, userID, addressIndex).Scan(&defaultAddress)
-	if err != nil {
-		r.Log.Error("Repository: failed to fetch address", zap.Error(err))
-		return nil, fmt.Errorf("failed to fetch address at index %d: %w", addressIndex, err)
-	}
-
-	response := &model.User{
-		DefaultAddress: defaultAddress,
-	}
-
-	return response, nil
-}
-func (r *authRepository) DeleteAddress(userID int, addressIndex int) (*model.User, error) {
-	var addressJSON []byte
-
-	addressQuery := `
-        UPDATE users 
-        SET address = address - $2 
-        WHERE id = $1 
-        RETURNING id, address;
-    `
-
-	var updatedUser model.User
-
-	err := r.DB.QueryRow(addressQuery, userID, addressIndex).Scan(
-		&updatedUser.ID,
-		&addressJSON,
-	)
-	if err != nil {
-		r.Log.Error("Repository: Failed to delete address", zap.Error(err))
-		return nil, fmt.Errorf("failed to delete address: %w", err)
-	}
-
-	if err := json.Unmarshal(addressJSON, &updatedUser.Address); err != nil {
-		r.Log.Error("Repository: Failed to unmarshal address JSON", zap.Error(err))
-		return nil, fmt.Errorf("failed to deserialize address: %w", err)
-	}
-
-	r.Log.Info("Repository: Deleted address successfully", zap.Int("userID", updatedUser.ID))
-	return &updatedUser, nil
-}
-
-func (r *authRepository) CreateAddress(userID int, newAddress string) (*model.User, error) {
-
-	var addressJSON []byte
-	err := r.DB.QueryRow(`SELECT address FROM users WHERE id = $1`, userID).Scan(&addressJSON)
-	if err != nil {
-		if err == sql.ErrNoRows {
-
-			addressJSON = []byte("[]")
-		} else {
-			return nil, fmt.Errorf("failed to fetch user address: %w", err)
-		}
-	}
-
-	var address []string
-	if len(addressJSON) > 0 {
-		if err := json.Unmarshal(addressJSON, &address); err != nil {
-			return nil, fmt.Errorf("failed to unmarshal address JSON: %w", err)
-		}
-	}
-
-	address = append(address, newAddress)
-
-	addressJSON, err = json.Marshal(address)
-	if err != nil {
-		return nil, fmt.Errorf("failed to marshal address: %w", err)
-	}
-
-	query := `
-        UPDATE users
-        SET address = $1
-        WHERE id = $2
-        RETURNING id, name, email, phone, address, updated_at
-    `
-
-	var user model.User
-	err = r.DB.QueryRow(query, addressJSON, userID).Scan(
-		&user.ID, &user.Name, &user.Email, &user.Phone, &addressJSON, &user.UpdatedAt,
-	)
-	if err != nil {
-		return nil, fmt.Errorf("failed to update user address: %w", err)
-	}
-	if err := json.Unmarshal(addressJSON, &user.Address); err != nil {
-		r.Log.Error("Repository: Failed to unmarshal address JSON", zap.Error(err))
-		return nil, fmt.Errorf("failed to deserialize address: %w", err)
-	}
-
-	return &user, nil
-}
+package repository
+
+import (
+	"context"
+	"crypto/subtle"
+	"database/sql"
+	"ecommerce/model"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+var (
+	ErrRefreshTokenInvalid = errors.New("invalid or expired refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+
+	ErrVerificationCodeInvalid = errors.New("invalid or expired code")
+	ErrVerificationCodeLocked  = errors.New("too many wrong attempts, request a new code")
+)
+
+type AuthRepository interface {
+	Create(user *model.User) error
+	GetUserLogin(user model.User) (*model.User, error)
+	GetPassword(userID int) (string, error)
+	UpdatePassword(userID int, password string) error
+	CreateSession(session *model.Session) error
+	GetSessionByTokenHash(tokenHash string) (*model.Session, error)
+	TouchSession(sessionID int) error
+	RotateRefreshToken(refreshHash string, session *model.Session) error
+	GetActiveSessions(userID int) ([]*model.Session, error)
+	RevokeSession(userID, sessionID int) error
+	RevokeAllSessions(userID int) (int64, error)
+	PurgeExpiredSessions() (int64, error)
+	RevokeSessionByTokenHash(tokenHash string) error
+	GetRevokedSessionIDs() (map[int]time.Time, error)
+	GetAllAddress(userID int) ([]*model.Address, error)
+	GetAddress(userID, addressID int) (*model.Address, error)
+	GetDetailUser(id int) (*model.User, error)
+	UpdateUser(userID int, name, email, password string) (*model.User, error)
+	CreateAddress(address *model.Address) error
+	UpdateAddress(address *model.Address) error
+	SetDefaultAddress(userID int, addressID int) (*model.User, error)
+	DeleteAddress(userID int, addressID int) error
+	UpdateRole(userID int, role string) (*model.User, error)
+	CreateVerificationCode(userID int, purpose, codeHash string, expiresAt time.Time) error
+	ConsumeVerificationCode(userID int, purpose, codeHash string, maxAttempts int) error
+	MarkContactVerified(userID int, purpose string) error
+	GetLoginBlockedUntil(accountKey, ip string) (*time.Time, error)
+	RecordLoginFailure(scope, key string, resetBefore time.Time) (int, error)
+	RecordCodeRequest(scope, key string, windowStart time.Time) (int, error)
+	BlockLogin(scope, key string, until time.Time) error
+	ClearLoginFailures(scope, key string) error
+	CreateAuditLog(entry *model.AuditLog) error
+}
+
+type authRepository struct {
+	DB  *sql.DB
+	Log *zap.Logger
+}
+
+func NewAuthRepository(db *sql.DB, logger *zap.Logger) AuthRepository {
+	return &authRepository{DB: db, Log: logger}
+}
+
+func (r *authRepository) Create(user *model.User) error {
+	var query string
+	var err error
+
+	if user.Email != "" {
+		query = `INSERT INTO users (name, email, password) VALUES ($1, $2, $3) RETURNING id`
+		r.Log.Info("Repository: Executing query", zap.String("query", query))
+		err = r.DB.QueryRow(query, user.Name, user.Email, user.Password).Scan(&user.ID)
+	} else if user.Phone != "" {
+		query = `INSERT INTO users (name, phone, password) VALUES ($1, $2, $3) RETURNING id`
+		r.Log.Info("Repository: Executing query", zap.String("query", query))
+		err = r.DB.QueryRow(query, user.Name, user.Phone, user.Password).Scan(&user.ID)
+	} else {
+		r.Log.Error("Repository: Validation failed", zap.String("reason", "either email or phone must be provided"))
+		return fmt.Errorf("either email or phone must be provided")
+	}
+
+	if err != nil {
+		r.Log.Error("Repository: Error executing query", zap.Error(err))
+		return err
+	}
+
+	r.Log.Info("Repository: User created successfully", zap.Int("userID", user.ID))
+	return nil
+
+}
+
+// GetUserLogin finds the user by email, or by phone when no email is given.
+// Only one identifier is ever matched, so a request cannot pair one
+// account's email with another account's phone.
+func (r *authRepository) GetUserLogin(user model.User) (*model.User, error) {
+	query := `SELECT id, name, email, phone, password, role FROM users WHERE email = $1`
+	identifier := user.Email
+	if identifier == "" {
+		query = `SELECT id, name, email, phone, password, role FROM users WHERE phone = $1`
+		identifier = user.Phone
+	}
+	r.Log.Info("Repository: Executing query", zap.String("query", query))
+
+	var userResponse model.User
+	var email sql.NullString
+	var phone sql.NullString
+	err := r.DB.QueryRow(query, identifier).Scan(&userResponse.ID, &userResponse.Name, &email, &phone, &userResponse.Password, &userResponse.Role)
+
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.Log.Warn("Repository: no user found with the provided credentials")
+			return nil, fmt.Errorf("invalid username or password")
+		}
+
+		r.Log.Error("Repository: Error executing query", zap.Error(err))
+		return nil, err
+	}
+
+	userResponse.Email = email.String
+	userResponse.Phone = phone.String
+
+	r.Log.Info("Repository: User found for login", zap.Int("userID", userResponse.ID))
+
+	return &userResponse, nil
+}
+
+func (r *authRepository) GetPassword(userID int) (string, error) {
+	var password string
+	err := r.DB.QueryRow(`SELECT password FROM users WHERE id = $1`, userID).Scan(&password)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.Log.Error("Repository: User not found", zap.Int("userID", userID))
+			return "", fmt.Errorf("user not found")
+		}
+		r.Log.Error("Repository: Failed to fetch user password", zap.Error(err))
+		return "", fmt.Errorf("failed to fetch user password: %w", err)
+	}
+	return password, nil
+}
+
+func (r *authRepository) UpdatePassword(userID int, password string) error {
+	_, err := r.DB.Exec(`UPDATE users SET password = $1, updated_at = NOW() WHERE id = $2`, password, userID)
+	if err != nil {
+		r.Log.Error("Repository: Failed to update password", zap.Int("userID", userID), zap.Error(err))
+		return fmt.Errorf("failed to update password: %w", err)
+	}
+	r.Log.Info("Repository: Password updated", zap.Int("userID", userID))
+	return nil
+}
+
+// RevokeSessionByTokenHash logs a session out. The row is kept until its
+// access token expires so stateless tokens can be rejected until then.
+func (r *authRepository) RevokeSessionByTokenHash(tokenHash string) error {
+	query := "UPDATE sessions SET revoked_at = NOW() WHERE token_hash = $1 AND revoked_at IS NULL"
+	res, err := r.DB.Exec(query, tokenHash)
+	if err != nil {
+		r.Log.Error("Repository: Failed to revoke session", zap.Error(err))
+		return err
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.Log.Warn("Repository: No session found with this token")
+	}
+
+	return nil
+}
+
+// GetRevokedSessionIDs returns the revoked sessions whose access token has
+// not expired yet, keyed by session id.
+func (r *authRepository) GetRevokedSessionIDs() (map[int]time.Time, error) {
+	rows, err := r.DB.Query(`SELECT id, expires_at FROM sessions WHERE revoked_at IS NOT NULL AND expires_at > NOW()`)
+	if err != nil {
+		r.Log.Error("Repository: Failed to query revoked sessions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	revoked := map[int]time.Time{}
+	for rows.Next() {
+		var sessionID int
+		var expiresAt time.Time
+		if err := rows.Scan(&sessionID, &expiresAt); err != nil {
+			r.Log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		revoked[sessionID] = expiresAt
+	}
+
+	return revoked, rows.Err()
+}
+
+func (r *authRepository) CreateSession(session *model.Session) error {
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	query := `
+        INSERT INTO sessions (user_id, token_hash, expires_at, user_agent, ip_address)
+        VALUES ($1, $2, $3, $4, $5)
+        RETURNING id, last_seen_at, created_at
+    `
+	err = tx.QueryRowContext(ctx, query, session.UserID, session.TokenHash, session.ExpiresAt, session.UserAgent, session.IPAddress).
+		Scan(&session.ID, &session.LastSeenAt, &session.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to create session", zap.Int("userID", session.UserID), zap.Error(err))
+		return fmt.Errorf("failed to create session: %w", err)
+	}
+
+	refreshQuery := `INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, refreshQuery, session.ID, session.RefreshHash, session.RefreshExpiresAt); err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to create refresh token", zap.Int("sessionID", session.ID), zap.Error(err))
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.Log.Info("Repository: Session created", zap.Int("userID", session.UserID), zap.Int("sessionID", session.ID))
+	return nil
+}
+
+func (r *authRepository) GetSessionByTokenHash(tokenHash string) (*model.Session, error) {
+	var session model.Session
+	query := `
+        SELECT s.id, s.user_id, s.token_hash, s.expires_at, u.role
+        FROM sessions s
+        JOIN users u ON s.user_id = u.id
+        WHERE s.token_hash = $1 AND s.revoked_at IS NULL
+    `
+	err := r.DB.QueryRow(query, tokenHash).Scan(&session.ID, &session.UserID, &session.TokenHash, &session.ExpiresAt, &session.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, fmt.Errorf("session not found or expired")
+		}
+		return nil, err
+	}
+	return &session, nil
+}
+
+// TouchSession records activity on a session. It writes at most once a
+// minute per session so authenticated requests do not all hit the table.
+func (r *authRepository) TouchSession(sessionID int) error {
+	query := `UPDATE sessions SET last_seen_at = NOW() WHERE id = $1 AND last_seen_at < NOW() - INTERVAL '1 minute'`
+	if _, err := r.DB.Exec(query, sessionID); err != nil {
+		r.Log.Warn("Repository: Failed to update session activity", zap.Int("sessionID", sessionID), zap.Error(err))
+		return err
+	}
+	return nil
+}
+
+// RotateRefreshToken spends the refresh token with hash refreshHash and stores the new access and
+// refresh tokens from session on the same session row. A refresh token that
+// was already spent means it leaked, so the whole session is revoked.
+func (r *authRepository) RotateRefreshToken(refreshHash string, session *model.Session) error {
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	var refreshID int
+	var expiresAt time.Time
+	var usedAt, revokedAt *time.Time
+	lookupQuery := `
+        SELECT rt.id, rt.expires_at, rt.used_at, s.id, s.user_id, s.revoked_at, u.role
+        FROM refresh_tokens rt
+        JOIN sessions s ON s.id = rt.session_id
+        JOIN users u ON u.id = s.user_id
+        WHERE rt.token_hash = $1
+        FOR UPDATE OF rt, s
+    `
+	err = tx.QueryRowContext(ctx, lookupQuery, refreshHash).Scan(&refreshID, &expiresAt, &usedAt, &session.ID, &session.UserID, &revokedAt, &session.Role)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			r.Log.Warn("Repository: Unknown refresh token")
+			return ErrRefreshTokenInvalid
+		}
+		r.Log.Error("Repository: Failed to look up refresh token", zap.Error(err))
+		return fmt.Errorf("failed to look up refresh token: %w", err)
+	}
+
+	if revokedAt != nil || expiresAt.Before(time.Now()) {
+		tx.Rollback()
+		r.Log.Warn("Repository: Refresh token expired or session revoked", zap.Int("sessionID", session.ID))
+		return ErrRefreshTokenInvalid
+	}
+
+	if usedAt != nil {
+		if _, err := tx.ExecContext(ctx, `UPDATE sessions SET revoked_at = NOW() WHERE id = $1`, session.ID); err != nil {
+			tx.Rollback()
+			r.Log.Error("Repository: Failed to revoke session", zap.Int("sessionID", session.ID), zap.Error(err))
+			return fmt.Errorf("failed to revoke session: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		r.Log.Warn("Repository: Refresh token reused, session revoked", zap.Int("userID", session.UserID), zap.Int("sessionID", session.ID))
+		return ErrRefreshTokenReused
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE refresh_tokens SET used_at = NOW() WHERE id = $1`, refreshID); err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to spend refresh token", zap.Error(err))
+		return fmt.Errorf("failed to spend refresh token: %w", err)
+	}
+
+	sessionQuery := `
+        UPDATE sessions
+        SET token_hash = $2, expires_at = $3, user_agent = $4, ip_address = $5, last_seen_at = NOW()
+        WHERE id = $1
+        RETURNING last_seen_at, created_at
+    `
+	err = tx.QueryRowContext(ctx, sessionQuery, session.ID, session.TokenHash, session.ExpiresAt, session.UserAgent, session.IPAddress).
+		Scan(&session.LastSeenAt, &session.CreatedAt)
+	if err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to rotate session token", zap.Int("sessionID", session.ID), zap.Error(err))
+		return fmt.Errorf("failed to rotate session token: %w", err)
+	}
+
+	refreshQuery := `INSERT INTO refresh_tokens (session_id, token_hash, expires_at) VALUES ($1, $2, $3)`
+	if _, err := tx.ExecContext(ctx, refreshQuery, session.ID, session.RefreshHash, session.RefreshExpiresAt); err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to create refresh token", zap.Int("sessionID", session.ID), zap.Error(err))
+		return fmt.Errorf("failed to create refresh token: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.Log.Info("Repository: Session refreshed", zap.Int("userID", session.UserID), zap.Int("sessionID", session.ID))
+	return nil
+}
+
+// GetActiveSessions lists sessions that can still be used, either directly
+// or through an unspent refresh token.
+func (r *authRepository) GetActiveSessions(userID int) ([]*model.Session, error) {
+	query := `
+        SELECT s.id, s.user_id, s.user_agent, s.ip_address, s.expires_at, s.last_seen_at, s.created_at
+        FROM sessions s
+        WHERE s.user_id = $1
+          AND s.revoked_at IS NULL
+          AND (s.expires_at > NOW() OR EXISTS (
+              SELECT 1 FROM refresh_tokens rt
+              WHERE rt.session_id = s.id AND rt.used_at IS NULL AND rt.expires_at > NOW()
+          ))
+        ORDER BY s.last_seen_at DESC
+    `
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		r.Log.Error("Repository: Failed to query sessions", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	sessions := []*model.Session{}
+	for rows.Next() {
+		var session model.Session
+		if err := rows.Scan(&session.ID, &session.UserID, &session.UserAgent, &session.IPAddress, &session.ExpiresAt, &session.LastSeenAt, &session.CreatedAt); err != nil {
+			r.Log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		sessions = append(sessions, &session)
+	}
+
+	return sessions, rows.Err()
+}
+
+func (r *authRepository) RevokeSession(userID, sessionID int) error {
+	res, err := r.DB.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE id = $1 AND user_id = $2 AND revoked_at IS NULL`, sessionID, userID)
+	if err != nil {
+		r.Log.Error("Repository: Failed to revoke session", zap.Error(err))
+		return fmt.Errorf("failed to revoke session: %w", err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.Log.Warn("Repository: Session not found", zap.Int("userID", userID), zap.Int("sessionID", sessionID))
+		return fmt.Errorf("session not found")
+	}
+
+	r.Log.Info("Repository: Session revoked", zap.Int("userID", userID), zap.Int("sessionID", sessionID))
+	return nil
+}
+
+func (r *authRepository) RevokeAllSessions(userID int) (int64, error) {
+	res, err := r.DB.Exec(`UPDATE sessions SET revoked_at = NOW() WHERE user_id = $1 AND revoked_at IS NULL`, userID)
+	if err != nil {
+		r.Log.Error("Repository: Failed to revoke sessions", zap.Error(err))
+		return 0, fmt.Errorf("failed to revoke sessions: %w", err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	r.Log.Info("Repository: All sessions revoked", zap.Int("userID", userID), zap.Int64("count", rowsAffected))
+	return rowsAffected, nil
+}
+
+// PurgeExpiredSessions deletes sessions whose access token has expired and
+// that are either revoked or have no usable refresh token left. Their
+// refresh tokens go with them.
+func (r *authRepository) PurgeExpiredSessions() (int64, error) {
+	query := `
+        DELETE FROM sessions s
+        WHERE s.expires_at < NOW()
+          AND (s.revoked_at IS NOT NULL OR NOT EXISTS (
+              SELECT 1 FROM refresh_tokens rt
+              WHERE rt.session_id = s.id AND rt.used_at IS NULL AND rt.expires_at > NOW()
+          ))
+    `
+	res, err := r.DB.Exec(query)
+	if err != nil {
+		r.Log.Error("Repository: Failed to purge sessions", zap.Error(err))
+		return 0, fmt.Errorf("failed to purge sessions: %w", err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	r.Log.Info("Repository: Expired sessions purged", zap.Int64("count", rowsAffected))
+	return rowsAffected, nil
+}
+
+func (r *authRepository) GetAllAddress(userID int) ([]*model.Address, error) {
+	query := `
+	SELECT a.id, a.user_id, a.label, a.recipient, a.phone, a.street, a.city, a.province, a.postal_code,
+		(a.id = u.default_address_id) IS TRUE, a.created_at, a.updated_at
+	FROM addresses a
+	JOIN users u ON u.id = a.user_id
+	WHERE a.user_id = $1
+	ORDER BY a.id ASC
+	`
+	rows, err := r.DB.Query(query, userID)
+	if err != nil {
+		r.Log.Error("Repository: Failed to query addresses", zap.Error(err))
+		return nil, err
+	}
+	defer rows.Close()
+
+	results := []*model.Address{}
+	for rows.Next() {
+		var address model.Address
+		if err := rows.Scan(&address.ID, &address.UserID, &address.Label, &address.Recipient, &address.Phone, &address.Street,
+			&address.City, &address.Province, &address.PostalCode, &address.IsDefault, &address.CreatedAt, &address.UpdatedAt); err != nil {
+			r.Log.Error("Repository: failed to scan row", zap.Error(err))
+			return nil, err
+		}
+		results = append(results, &address)
+	}
+
+	return results, rows.Err()
+}
+
+func (r *authRepository) GetAddress(userID, addressID int) (*model.Address, error) {
+	query := `
+	SELECT a.id, a.user_id, a.label, a.recipient, a.phone, a.street, a.city, a.province, a.postal_code,
+		(a.id = u.default_address_id) IS TRUE, a.created_at, a.updated_at
+	FROM addresses a
+	JOIN users u ON u.id = a.user_id
+	WHERE a.id = $1 AND a.user_id = $2
+	`
+	var address model.Address
+	err := r.DB.QueryRow(query, addressID, userID).Scan(&address.ID, &address.UserID, &address.Label, &address.Recipient, &address.Phone,
+		&address.Street, &address.City, &address.Province, &address.PostalCode, &address.IsDefault, &address.CreatedAt, &address.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.Log.Warn("Repository: Address not found", zap.Int("userID", userID), zap.Int("addressID", addressID))
+			return nil, fmt.Errorf("address not found")
+		}
+		r.Log.Error("Repository: Failed to query address", zap.Error(err))
+		return nil, err
+	}
+	return &address, nil
+}
+
+func (r *authRepository) GetDetailUser(id int) (*model.User, error) {
+	var user model.User
+	var emailVerifiedAt, phoneVerifiedAt *time.Time
+
+	query := `SELECT name, COALESCE(email, ''), COALESCE(phone, ''), role, email_verified_at, phone_verified_at FROM users WHERE id = $1`
+	err := r.DB.QueryRow(query, id).
+		Scan(&user.Name, &user.Email, &user.Phone, &user.Role, &emailVerifiedAt, &phoneVerifiedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.Log.Warn("Repository: User not found", zap.Int("id", id))
+			return nil, fmt.Errorf("user with id %d not found", id)
+		}
+		r.Log.Error("Repository: Failed to query user", zap.Error(err))
+		return nil, err
+	}
+	r.Log.Info("Repository: Executing query", zap.Int("user_id", id))
+
+	// Only contacts the user actually has are reported; the account counts
+	// as unverified until at least one of them is confirmed.
+	if user.Email != "" {
+		verified := emailVerifiedAt != nil
+		user.EmailVerified = &verified
+	}
+	if user.Phone != "" {
+		verified := phoneVerifiedAt != nil
+		user.PhoneVerified = &verified
+	}
+	user.Unverified = emailVerifiedAt == nil && phoneVerifiedAt == nil
+
+	addresses, err := r.GetAllAddress(id)
+	if err != nil {
+		return nil, err
+	}
+	for _, address := range addresses {
+		user.Address = append(user.Address, *address)
+		if address.IsDefault {
+			user.DefaultAddress = address
+		}
+	}
+
+	r.Log.Info("Repository: Retrieved user details", zap.Int("userID", user.ID))
+	return &user, nil
+}
+
+func (r *authRepository) UpdateUser(userID int, name, email, password string) (*model.User, error) {
+	r.Log.Info("Repository: Updating user details", zap.Int("userID", userID))
+
+	query := `
+        UPDATE users
+        SET name = $1, email = $2, password = $3, updated_at = NOW(),
+            email_verified_at = CASE WHEN email IS DISTINCT FROM $2 THEN NULL ELSE email_verified_at END
+        WHERE id = $4
+        RETURNING id, name, email
+    `
+
+	var updatedUser model.User
+	err := r.DB.QueryRow(query, name, email, password, userID).Scan(
+		&updatedUser.ID,
+		&updatedUser.Name,
+		&updatedUser.Email,
+	)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.Log.Error("Repository: User not found", zap.Int("userID", userID))
+			return nil, fmt.Errorf("user not found")
+		}
+		r.Log.Error("Repository: Failed to update user", zap.Error(err))
+		return nil, fmt.Errorf("failed to update user: %w", err)
+	}
+
+	r.Log.Info("Repository: User details updated successfully", zap.Int("userID", updatedUser.ID))
+	return &updatedUser, nil
+}
+
+func (r *authRepository) CreateAddress(address *model.Address) error {
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	query := `
+        INSERT INTO addresses (user_id, label, recipient, phone, street, city, province, postal_code)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8)
+        RETURNING id, created_at, updated_at
+    `
+	err = tx.QueryRowContext(ctx, query, address.UserID, address.Label, address.Recipient, address.Phone, address.Street, address.City,
+		address.Province, address.PostalCode).Scan(&address.ID, &address.CreatedAt, &address.UpdatedAt)
+	if err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to create address", zap.Error(err))
+		return fmt.Errorf("failed to create address: %w", err)
+	}
+
+	// A user's first address becomes their default.
+	res, err := tx.ExecContext(ctx, `UPDATE users SET default_address_id = $2 WHERE id = $1 AND default_address_id IS NULL`, address.UserID, address.ID)
+	if err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to set default address", zap.Error(err))
+		return fmt.Errorf("failed to set default address: %w", err)
+	}
+	rowsAffected, _ := res.RowsAffected()
+	address.IsDefault = rowsAffected > 0
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.Log.Info("Repository: Address created successfully", zap.Int("userID", address.UserID), zap.Int("addressID", address.ID))
+	return nil
+}
+
+func (r *authRepository) UpdateAddress(address *model.Address) error {
+	query := `
+        UPDATE addresses
+        SET label = $3, recipient = $4, phone = $5, street = $6, city = $7, province = $8, postal_code = $9, updated_at = NOW()
+        WHERE id = $1 AND user_id = $2
+        RETURNING (id = (SELECT default_address_id FROM users WHERE id = $2)) IS TRUE, created_at, updated_at
+    `
+	err := r.DB.QueryRow(query, address.ID, address.UserID, address.Label, address.Recipient, address.Phone, address.Street,
+		address.City, address.Province, address.PostalCode).Scan(&address.IsDefault, &address.CreatedAt, &address.UpdatedAt)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.Log.Warn("Repository: Address not found", zap.Int("userID", address.UserID), zap.Int("addressID", address.ID))
+			return fmt.Errorf("address not found")
+		}
+		r.Log.Error("Repository: Failed to update address", zap.Error(err))
+		return fmt.Errorf("failed to update address: %w", err)
+	}
+
+	r.Log.Info("Repository: Address updated successfully", zap.Int("userID", address.UserID), zap.Int("addressID", address.ID))
+	return nil
+}
+
+func (r *authRepository) SetDefaultAddress(userID int, addressID int) (*model.User, error) {
+	query := `
+        UPDATE users u
+        SET default_address_id = a.id, updated_at = NOW()
+        FROM addresses a
+        WHERE u.id = $1 AND a.id = $2 AND a.user_id = u.id
+    `
+	res, err := r.DB.Exec(query, userID, addressID)
+	if err != nil {
+		r.Log.Error("Repository: Failed to set default address", zap.Error(err))
+		return nil, fmt.Errorf("failed to set default address: %w", err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		r.Log.Warn("Repository: Address not found", zap.Int("userID", userID), zap.Int("addressID", addressID))
+		return nil, fmt.Errorf("address not found")
+	}
+
+	address, err := r.GetAddress(userID, addressID)
+	if err != nil {
+		return nil, err
+	}
+
+	r.Log.Info("Repository: Default address updated", zap.Int("userID", userID), zap.Int("addressID", addressID))
+	return &model.User{ID: userID, DefaultAddress: address}, nil
+}
+
+func (r *authRepository) DeleteAddress(userID int, addressID int) error {
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	res, err := tx.ExecContext(ctx, `DELETE FROM addresses WHERE id = $1 AND user_id = $2`, addressID, userID)
+	if err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to delete address", zap.Error(err))
+		return fmt.Errorf("failed to delete address: %w", err)
+	}
+
+	rowsAffected, _ := res.RowsAffected()
+	if rowsAffected == 0 {
+		tx.Rollback()
+		r.Log.Warn("Repository: Address not found", zap.Int("userID", userID), zap.Int("addressID", addressID))
+		return fmt.Errorf("address not found")
+	}
+
+	// Deleting the default clears users.default_address_id; the oldest
+	// remaining address takes its place.
+	reassignQuery := `
+        UPDATE users
+        SET default_address_id = (SELECT id FROM addresses WHERE user_id = $1 ORDER BY id ASC LIMIT 1)
+        WHERE id = $1 AND default_address_id IS NULL
+    `
+	if _, err := tx.ExecContext(ctx, reassignQuery, userID); err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to reassign default address", zap.Error(err))
+		return fmt.Errorf("failed to reassign default address: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.Log.Info("Repository: Deleted address successfully", zap.Int("userID", userID), zap.Int("addressID", addressID))
+	return nil
+}
+
+func (r *authRepository) UpdateRole(userID int, role string) (*model.User, error) {
+	query := `
+        UPDATE users
+        SET role = $1, updated_at = NOW()
+        WHERE id = $2
+        RETURNING id, name, role
+    `
+
+	var user model.User
+	err := r.DB.QueryRow(query, role, userID).Scan(&user.ID, &user.Name, &user.Role)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			r.Log.Warn("Repository: User not found", zap.Int("userID", userID))
+			return nil, fmt.Errorf("user not found")
+		}
+		r.Log.Error("Repository: Failed to update role", zap.Error(err))
+		return nil, fmt.Errorf("failed to update role: %w", err)
+	}
+
+	r.Log.Info("Repository: User role updated", zap.Int("userID", user.ID), zap.String("role", user.Role))
+	return &user, nil
+}
+
+// CreateVerificationCode stores a new code for purpose and retires the
+// user's earlier unused ones, so only the latest code sent works.
+func (r *authRepository) CreateVerificationCode(userID int, purpose, codeHash string, expiresAt time.Time) error {
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	retireQuery := `UPDATE verification_codes SET consumed_at = NOW() WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL`
+	if _, err := tx.ExecContext(ctx, retireQuery, userID, purpose); err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to retire verification codes", zap.Error(err))
+		return fmt.Errorf("failed to retire verification codes: %w", err)
+	}
+
+	insertQuery := `INSERT INTO verification_codes (user_id, purpose, code_hash, expires_at) VALUES ($1, $2, $3, $4)`
+	if _, err := tx.ExecContext(ctx, insertQuery, userID, purpose, codeHash, expiresAt); err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to create verification code", zap.Error(err))
+		return fmt.Errorf("failed to create verification code: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.Log.Info("Repository: Verification code created", zap.Int("userID", userID), zap.String("purpose", purpose))
+	return nil
+}
+
+// ConsumeVerificationCode checks codeHash against the user's latest code for
+// purpose. A wrong guess counts as an attempt; after maxAttempts the code is
+// locked even for the right value.
+func (r *authRepository) ConsumeVerificationCode(userID int, purpose, codeHash string, maxAttempts int) error {
+	ctx := context.Background()
+	tx, err := r.DB.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to start transaction: %w", err)
+	}
+
+	var id, attempts int
+	var storedHash string
+	var expiresAt time.Time
+	query := `
+        SELECT id, code_hash, attempts, expires_at
+        FROM verification_codes
+        WHERE user_id = $1 AND purpose = $2 AND consumed_at IS NULL
+        ORDER BY id DESC
+        LIMIT 1
+        FOR UPDATE
+    `
+	err = tx.QueryRowContext(ctx, query, userID, purpose).Scan(&id, &storedHash, &attempts, &expiresAt)
+	if err != nil {
+		tx.Rollback()
+		if errors.Is(err, sql.ErrNoRows) {
+			return ErrVerificationCodeInvalid
+		}
+		r.Log.Error("Repository: Failed to query verification code", zap.Error(err))
+		return fmt.Errorf("failed to query verification code: %w", err)
+	}
+
+	if expiresAt.Before(time.Now()) {
+		tx.Rollback()
+		return ErrVerificationCodeInvalid
+	}
+	if attempts >= maxAttempts {
+		tx.Rollback()
+		r.Log.Warn("Repository: Verification code locked", zap.Int("userID", userID), zap.String("purpose", purpose))
+		return ErrVerificationCodeLocked
+	}
+
+	if subtle.ConstantTimeCompare([]byte(storedHash), []byte(codeHash)) != 1 {
+		if _, err := tx.ExecContext(ctx, `UPDATE verification_codes SET attempts = attempts + 1 WHERE id = $1`, id); err != nil {
+			tx.Rollback()
+			r.Log.Error("Repository: Failed to record verification attempt", zap.Error(err))
+			return fmt.Errorf("failed to record verification attempt: %w", err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("failed to commit transaction: %w", err)
+		}
+		r.Log.Warn("Repository: Wrong verification code", zap.Int("userID", userID), zap.String("purpose", purpose), zap.Int("attempts", attempts+1))
+		return ErrVerificationCodeInvalid
+	}
+
+	if _, err := tx.ExecContext(ctx, `UPDATE verification_codes SET consumed_at = NOW() WHERE id = $1`, id); err != nil {
+		tx.Rollback()
+		r.Log.Error("Repository: Failed to consume verification code", zap.Error(err))
+		return fmt.Errorf("failed to consume verification code: %w", err)
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to commit transaction: %w", err)
+	}
+
+	r.Log.Info("Repository: Verification code consumed", zap.Int("userID", userID), zap.String("purpose", purpose))
+	return nil
+}
+
+func (r *authRepository) MarkContactVerified(userID int, purpose string) error {
+	var query string
+	switch purpose {
+	case model.PurposeVerifyEmail:
+		query = `UPDATE users SET email_verified_at = NOW(), updated_at = NOW() WHERE id = $1`
+	case model.PurposeVerifyPhone:
+		query = `UPDATE users SET phone_verified_at = NOW(), updated_at = NOW() WHERE id = $1`
+	default:
+		return fmt.Errorf("unknown verification purpose %q", purpose)
+	}
+
+	if _, err := r.DB.Exec(query, userID); err != nil {
+		r.Log.Error("Repository: Failed to mark contact verified", zap.Error(err))
+		return fmt.Errorf("failed to mark contact verified: %w", err)
+	}
+
+	r.Log.Info("Repository: Contact verified", zap.Int("userID", userID), zap.String("purpose", purpose))
+	return nil
+}
+
+// GetLoginBlockedUntil returns the latest block on either the identifier or
+// the IP, or nil when neither is blocked.
+func (r *authRepository) GetLoginBlockedUntil(accountKey, ip string) (*time.Time, error) {
+	query := `
+	SELECT MAX(blocked_until) FROM login_throttles
+	WHERE ((scope = $1 AND key = $2) OR (scope = $3 AND key = $4)) AND blocked_until > NOW()
+	`
+	var blockedUntil sql.NullTime
+	err := r.DB.QueryRow(query, model.LoginScopeAccount, accountKey, model.LoginScopeIP, ip).Scan(&blockedUntil)
+	if err != nil {
+		r.Log.Error("Repository: Failed to check login throttle", zap.Error(err))
+		return nil, fmt.Errorf("failed to check login throttle: %w", err)
+	}
+	if !blockedUntil.Valid {
+		return nil, nil
+	}
+	return &blockedUntil.Time, nil
+}
+
+// RecordLoginFailure counts one failed login and returns the running total.
+// Counts whose last failure is older than resetBefore start over.
+func (r *authRepository) RecordLoginFailure(scope, key string, resetBefore time.Time) (int, error) {
+	query := `
+	INSERT INTO login_throttles (scope, key, failures, last_failure_at) VALUES ($1, $2, 1, NOW())
+	ON CONFLICT (scope, key) DO UPDATE SET
+		failures = CASE WHEN login_throttles.last_failure_at < $3 THEN 1 ELSE login_throttles.failures + 1 END,
+		blocked_until = CASE WHEN login_throttles.last_failure_at < $3 THEN NULL ELSE login_throttles.blocked_until END,
+		last_failure_at = NOW()
+	RETURNING failures
+	`
+	var failures int
+	if err := r.DB.QueryRow(query, scope, key, resetBefore).Scan(&failures); err != nil {
+		r.Log.Error("Repository: Failed to record login failure", zap.Error(err))
+		return 0, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return failures, nil
+}
+
+// RecordCodeRequest counts one issued code and returns the total for the
+// current window. A window that started before windowStart is replaced by a
+// new one, so steady requests cannot keep an old count alive.
+func (r *authRepository) RecordCodeRequest(scope, key string, windowStart time.Time) (int, error) {
+	query := `
+	INSERT INTO code_request_throttles (scope, key, requests, window_started_at) VALUES ($1, $2, 1, NOW())
+	ON CONFLICT (scope, key) DO UPDATE SET
+		requests = CASE WHEN code_request_throttles.window_started_at < $3 THEN 1 ELSE code_request_throttles.requests + 1 END,
+		window_started_at = CASE WHEN code_request_throttles.window_started_at < $3 THEN NOW() ELSE code_request_throttles.window_started_at END
+	RETURNING requests
+	`
+	var requests int
+	if err := r.DB.QueryRow(query, scope, key, windowStart).Scan(&requests); err != nil {
+		r.Log.Error("Repository: Failed to record code request", zap.Error(err))
+		return 0, fmt.Errorf("failed to record code request: %w", err)
+	}
+	return requests, nil
+}
+
+func (r *authRepository) BlockLogin(scope, key string, until time.Time) error {
+	_, err := r.DB.Exec(`UPDATE login_throttles SET blocked_until = $3 WHERE scope = $1 AND key = $2`, scope, key, until)
+	if err != nil {
+		r.Log.Error("Repository: Failed to block login", zap.Error(err))
+		return fmt.Errorf("failed to block login: %w", err)
+	}
+	return nil
+}
+
+func (r *authRepository) ClearLoginFailures(scope, key string) error {
+	_, err := r.DB.Exec(`DELETE FROM login_throttles WHERE scope = $1 AND key = $2`, scope, key)
+	if err != nil {
+		r.Log.Error("Repository: Failed to clear login failures", zap.Error(err))
+		return fmt.Errorf("failed to clear login failures: %w", err)
+	}
+	return nil
+}
+
+func (r *authRepository) CreateAuditLog(entry *model.AuditLog) error {
+	detail := entry.Detail
+	if detail == nil {
+		detail = map[string]interface{}{}
+	}
+	detailJSON, err := json.Marshal(detail)
+	if err != nil {
+		return fmt.Errorf("failed to serialize audit detail: %w", err)
+	}
+
+	query := `
+	INSERT INTO audit_logs (event, user_id, identifier, ip_address, detail) VALUES ($1, $2, $3, $4, $5)
+	RETURNING id, created_at
+	`
+	err = r.DB.QueryRow(query, entry.Event, entry.UserID, entry.Identifier, entry.IPAddress, detailJSON).Scan(&entry.ID, &entry.CreatedAt)
+	if err != nil {
+		r.Log.Error("Repository: Failed to create audit log", zap.Error(err))
+		return fmt.Errorf("failed to create audit log: %w", err)
+	}
+
+	r.Log.Info("Repository: Audit log created", zap.String("event", entry.Event), zap.Int("id", entry.ID))
+	return nil
+}
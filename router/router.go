@@ -1,92 +1,155 @@
-package router
-
-import (
-	"ecommerce/handler"
-	middleware_auth "ecommerce/middleware"
-	"ecommerce/service"
-	"ecommerce/util"
-	"net/http"
-	"time"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/go-chi/chi/v5/middleware"
-	"go.uber.org/zap"
-)
-
-func NewRouter(checkoutHandler *handler.CheckoutHandler, homePageHandler *handler.HomePageHandler, authHandler *handler.AuthHandler, authService service.AuthService, log *zap.Logger) (*chi.Mux, error) {
-
-	r := chi.NewRouter()
-
-	config := util.ReadConfiguration()
-
-	log.Info("Loaded configuration", zap.String("AppName", config.AppName), zap.String("Port", config.Port))
-	log.Debug("Loaded configuration", zap.String("AppName", config.AppName), zap.String("Port", config.Port))
-
-	r.Use(middleware.Logger)
-
-	r.Use(func(next http.Handler) http.Handler {
-		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-			start := time.Now()
-
-			log.Info("Request received", zap.String("method", r.Method), zap.String("url", r.URL.String()))
-
-			next.ServeHTTP(w, r)
-
-			duration := time.Since(start)
-
-			log.Info("Request processed", zap.String("method", r.Method), zap.String("url", r.URL.String()), zap.Duration("duration", duration))
-		})
-	})
-
-	// fileServer := http.FileServer(http.Dir("./uploads"))
-	// r.Handle("/uploads/*", http.StripPrefix("/uploads", fileServer))
-
-	r.Group(func(r chi.Router) {
-		r.Post("/login", authHandler.LoginHandler)
-		r.Post("/register", authHandler.RegisterHandler)
-		r.Post("/logout", authHandler.LogoutHandler)
-	})
-
-	authMiddleware := middleware_auth.NewAuthMiddleware(authService, log)
-
-	r.Group(func(r chi.Router) {
-		r.Route("/api/account", func(r chi.Router) {
-			r.With(authMiddleware.Middleware).Get("/address", authHandler.GetAllAddressHandler)
-			r.With(authMiddleware.Middleware).Get("/detail-user", authHandler.GetDetailUserHandler)
-			r.With(authMiddleware.Middleware).Put("/update-user", authHandler.UpdateUserHandler)
-			r.With(authMiddleware.Middleware).Put("/address", authHandler.UpdateAddressUserHandler)
-			r.With(authMiddleware.Middleware).Delete("/address", authHandler.DeleteAddressHandler)
-			r.With(authMiddleware.Middleware).Post("/address-default", authHandler.SetDefaultAddressUserHandler)
-			r.With(authMiddleware.Middleware).Post("/address", authHandler.CreateAddressHandler)
-
-		})
-	})
-
-	r.Group(func(r chi.Router) {
-		r.Route("/api/products", func(r chi.Router) {
-			r.Get("/", homePageHandler.GetAllProductsHandler)
-			r.Get("/best-selling", homePageHandler.GetAllBestSellingProductsHandler)
-			r.Get("/weekly-promotion", homePageHandler.GetAllWeeklyPromotionProductsHandler)
-			r.Get("/recomments", homePageHandler.GetAllRecommentsProductsHandler)
-			r.Get("/{id}", homePageHandler.GetByIdProductHandler)
-
-			r.With(authMiddleware.Middleware).Get("/carts", checkoutHandler.GetAllCartHandler)
-			r.With(authMiddleware.Middleware).Post("/carts", checkoutHandler.AddCartHandler)
-			r.With(authMiddleware.Middleware).Post("/orders", checkoutHandler.CreateOrderHandler)
-			r.With(authMiddleware.Middleware).Put("/carts/{id}", checkoutHandler.UpdateCartHandler)
-			r.With(authMiddleware.Middleware).Delete("/carts/{id}", checkoutHandler.DeleteCartHandler)
-			r.With(authMiddleware.Middleware).Get("/total-carts", checkoutHandler.GetTotalCartHandler)
-			r.With(authMiddleware.Middleware).Post("/wishlist", homePageHandler.AddWishlistHandler)
-			r.With(authMiddleware.Middleware).Delete("/wishlist/{id}", homePageHandler.DeleteWishlistHandler)
-
-		})
-		r.Route("/api/categories", func(r chi.Router) {
-			r.Get("/", homePageHandler.GetAllCategoriesHandler)
-		})
-		r.Route("/api/banners", func(r chi.Router) {
-			r.Get("/", homePageHandler.GetAllBannersHandler)
-		})
-	})
-
-	return r, nil
-}
+package router
+
+import (
+	"ecommerce/handler"
+	middleware_auth "ecommerce/middleware"
+	"ecommerce/model"
+	"ecommerce/service"
+	"ecommerce/util"
+	"net/http"
+	"time"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+)
+
+func NewRouter(checkoutHandler *handler.CheckoutHandler, cartHandler *handler.CartHandler, homePageHandler *handler.HomePageHandler, authHandler *handler.AuthHandler, reviewHandler *handler.ReviewHandler, catalogHandler *handler.CatalogHandler, paymentHandler *handler.PaymentHandler, couponHandler *handler.CouponHandler, pricingHandler *handler.PricingHandler, authService service.AuthService, log *zap.Logger) (*chi.Mux, error) {
+
+	r := chi.NewRouter()
+
+	config := util.ReadConfiguration()
+
+	log.Info("Loaded configuration", zap.String("AppName", config.AppName), zap.String("Port", config.Port))
+	log.Debug("Loaded configuration", zap.String("AppName", config.AppName), zap.String("Port", config.Port))
+
+	r.Use(middleware.Logger)
+
+	r.Use(func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			start := time.Now()
+
+			log.Info("Request received", zap.String("method", r.Method), zap.String("url", r.URL.String()))
+
+			next.ServeHTTP(w, r)
+
+			duration := time.Since(start)
+
+			log.Info("Request processed", zap.String("method", r.Method), zap.String("url", r.URL.String()), zap.Duration("duration", duration))
+		})
+	})
+
+	// fileServer := http.FileServer(http.Dir("./uploads"))
+	// r.Handle("/uploads/*", http.StripPrefix("/uploads", fileServer))
+
+	r.Group(func(r chi.Router) {
+		r.Post("/login", authHandler.LoginHandler)
+		r.Post("/refresh", authHandler.RefreshTokenHandler)
+		r.Post("/password/forgot", authHandler.ForgotPasswordHandler)
+		r.Post("/password/reset", authHandler.ResetPasswordHandler)
+		r.Post("/register", authHandler.RegisterHandler)
+		r.Post("/logout", authHandler.LogoutHandler)
+	})
+
+	r.Post("/webhooks/payments", paymentHandler.PaymentWebhookHandler)
+
+	authMiddleware := middleware_auth.NewAuthMiddleware(authService, log)
+
+	r.Group(func(r chi.Router) {
+		r.Route("/api/account", func(r chi.Router) {
+			r.With(authMiddleware.Middleware).Get("/address", authHandler.GetAllAddressHandler)
+			r.With(authMiddleware.Middleware).Get("/detail-user", authHandler.GetDetailUserHandler)
+			r.With(authMiddleware.Middleware).Put("/update-user", authHandler.UpdateUserHandler)
+			r.With(authMiddleware.Middleware).Put("/address/{id}", authHandler.UpdateAddressHandler)
+			r.With(authMiddleware.Middleware).Delete("/address/{id}", authHandler.DeleteAddressHandler)
+			r.With(authMiddleware.Middleware).Post("/address-default", authHandler.SetDefaultAddressUserHandler)
+			r.With(authMiddleware.Middleware).Post("/address", authHandler.CreateAddressHandler)
+			r.With(authMiddleware.Middleware).Get("/sessions", authHandler.GetSessionsHandler)
+			r.With(authMiddleware.Middleware).Delete("/sessions", authHandler.RevokeAllSessionsHandler)
+			r.With(authMiddleware.Middleware).Delete("/sessions/{id}", authHandler.RevokeSessionHandler)
+			r.With(authMiddleware.Middleware).Post("/verify/request", authHandler.RequestVerificationHandler)
+			r.With(authMiddleware.Middleware).Post("/verify/confirm", authHandler.ConfirmVerificationHandler)
+
+		})
+	})
+
+	r.Group(func(r chi.Router) {
+		r.Route("/api/products", func(r chi.Router) {
+			r.Get("/", homePageHandler.GetAllProductsHandler)
+			r.Get("/search", homePageHandler.SearchProductsHandler)
+			r.Get("/best-selling", homePageHandler.GetAllBestSellingProductsHandler)
+			r.Get("/weekly-promotion", homePageHandler.GetAllWeeklyPromotionProductsHandler)
+			r.Get("/recomments", homePageHandler.GetAllRecommentsProductsHandler)
+			r.Get("/{id}", homePageHandler.GetByIdProductHandler)
+			r.With(authMiddleware.OptionalMiddleware).Get("/{id}/price", pricingHandler.GetPriceHandler)
+			r.Get("/{id}/reviews", reviewHandler.GetReviewsByProductHandler)
+			r.With(authMiddleware.Middleware).Post("/{id}/reviews", reviewHandler.CreateReviewHandler)
+
+			r.With(authMiddleware.Middleware).Get("/carts", cartHandler.GetAllCartHandler)
+			r.With(authMiddleware.Middleware).Post("/carts", cartHandler.AddCartHandler)
+			r.With(authMiddleware.Middleware).Post("/orders", checkoutHandler.CreateOrderHandler)
+			r.With(authMiddleware.Middleware).Put("/carts/{id}", cartHandler.UpdateCartHandler)
+			r.With(authMiddleware.Middleware).Delete("/carts/{id}", cartHandler.DeleteCartHandler)
+			r.With(authMiddleware.Middleware).Post("/carts/apply-coupon", couponHandler.ApplyCouponHandler)
+			r.With(authMiddleware.Middleware).Post("/carts/shipping-rates", checkoutHandler.GetShippingRatesHandler)
+			r.With(authMiddleware.Middleware).Get("/total-carts", cartHandler.GetTotalCartHandler)
+			r.With(authMiddleware.Middleware).Post("/wishlist", homePageHandler.AddWishlistHandler)
+			r.With(authMiddleware.Middleware).Delete("/wishlist/{id}", homePageHandler.DeleteWishlistHandler)
+			r.With(authMiddleware.Middleware).Get("/wishlist", homePageHandler.GetAllWishlistHandler)
+			r.With(authMiddleware.Middleware).Post("/wishlist/{id}/move-to-cart", cartHandler.MoveWishlistToCartHandler)
+			r.With(authMiddleware.Middleware).Post("/wishlist/share", homePageHandler.CreateWishlistShareHandler)
+			r.With(authMiddleware.Middleware).Delete("/wishlist/share", homePageHandler.DeleteWishlistShareHandler)
+			r.Get("/wishlist/shared/{token}", homePageHandler.GetSharedWishlistHandler)
+
+		})
+		r.Route("/api/orders", func(r chi.Router) {
+			r.With(authMiddleware.Middleware).Get("/", checkoutHandler.GetAllOrdersHandler)
+			r.With(authMiddleware.Middleware).Get("/{id}", checkoutHandler.GetOrderByIdHandler)
+			r.With(authMiddleware.Middleware).Post("/{id}/cancel", checkoutHandler.CancelOrderHandler)
+			r.With(authMiddleware.Middleware).Post("/{id}/payments", paymentHandler.CreatePaymentHandler)
+			r.With(authMiddleware.Middleware).Get("/{id}/payments", paymentHandler.GetPaymentHandler)
+		})
+		r.Route("/api/reviews", func(r chi.Router) {
+			r.With(authMiddleware.Middleware).Put("/{id}", reviewHandler.UpdateReviewHandler)
+			r.With(authMiddleware.Middleware).Delete("/{id}", reviewHandler.DeleteReviewHandler)
+		})
+		r.Route("/api/admin", func(r chi.Router) {
+			r.Use(authMiddleware.Middleware)
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequireRole(model.RoleStaff, model.RoleAdmin))
+				r.Put("/orders/{id}/status", checkoutHandler.UpdateOrderStatusHandler)
+				r.Post("/orders/{id}/refund", paymentHandler.RefundPaymentHandler)
+			})
+
+			r.Group(func(r chi.Router) {
+				r.Use(authMiddleware.RequireRole(model.RoleAdmin))
+				r.Post("/products", catalogHandler.CreateProductHandler)
+				r.Get("/products/{id}", catalogHandler.GetProductHandler)
+				r.Put("/products/{id}", catalogHandler.UpdateProductHandler)
+				r.Delete("/products/{id}", catalogHandler.DeleteProductHandler)
+
+				r.Post("/categories", catalogHandler.CreateCategoryHandler)
+				r.Put("/categories/{id}", catalogHandler.UpdateCategoryHandler)
+				r.Delete("/categories/{id}", catalogHandler.DeleteCategoryHandler)
+
+				r.Put("/users/{id}/role", authHandler.UpdateRoleHandler)
+
+				r.Get("/coupons", couponHandler.GetAllCouponsHandler)
+				r.Post("/coupons", couponHandler.CreateCouponHandler)
+				r.Delete("/coupons/{id}", couponHandler.DeactivateCouponHandler)
+			})
+		})
+		r.Route("/api/categories", func(r chi.Router) {
+			r.Get("/", homePageHandler.GetAllCategoriesHandler)
+		})
+		r.Route("/api/shipping", func(r chi.Router) {
+			r.Get("/methods", checkoutHandler.GetShippingMethodsHandler)
+		})
+		r.Route("/api/banners", func(r chi.Router) {
+			r.Get("/", homePageHandler.GetAllBannersHandler)
+		})
+	})
+
+	return r, nil
+}
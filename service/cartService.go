@@ -0,0 +1,52 @@
+package service
+
+import (
+	"ecommerce/model"
+	"ecommerce/repository"
+)
+
+type CartService struct {
+	Repo repository.CartRepository
+}
+
+func NewCartService(repo repository.CartRepository) CartService {
+	return CartService{Repo: repo}
+}
+
+// GetAllCartService reprices the cart before listing it; lines whose price
+// changed or that can no longer be bought carry a notice.
+func (s *CartService) GetAllCartService(userID int) ([]*model.Checkout, error) {
+	notices, err := s.Repo.RepriceCart(userID, nil)
+	if err != nil {
+		return nil, err
+	}
+
+	carts, err := s.Repo.GetAllCart(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	byLine := make(map[int]*model.CartNotice, len(notices))
+	for i := range notices {
+		byLine[notices[i].CartItemID] = &notices[i]
+	}
+	for _, cart := range carts {
+		cart.Notice = byLine[cart.ID]
+	}
+	return carts, nil
+}
+func (s *CartService) GetTotalCartService(userID int) (*model.Checkout, error) {
+	return s.Repo.GetTotalCart(userID)
+}
+func (s *CartService) AddCartService(cart model.Checkout) error {
+	return s.Repo.AddCart(cart)
+}
+func (s *CartService) MoveWishlistToCartService(wishlistID, userID int, variant map[string]string) (*model.Checkout, error) {
+	return s.Repo.MoveWishlistToCart(wishlistID, userID, variant)
+}
+func (s *CartService) DeleteCartService(id, userID int) error {
+	return s.Repo.DeleteCart(id, userID)
+}
+func (s *CartService) UpdateCartService(userID, id, quantity int) (*model.Checkout, error) {
+	return s.Repo.UpdateCart(userID, id, quantity)
+}
@@ -0,0 +1,36 @@
+package service
+
+import (
+	"ecommerce/model"
+	"ecommerce/repository"
+)
+
+type CatalogService struct {
+	Repo repository.CatalogRepository
+}
+
+func NewCatalogService(repo repository.CatalogRepository) CatalogService {
+	return CatalogService{Repo: repo}
+}
+
+func (s *CatalogService) GetProductService(id int) (*model.CatalogProduct, error) {
+	return s.Repo.GetProduct(id)
+}
+func (s *CatalogService) CreateProductService(product *model.CatalogProduct) error {
+	return s.Repo.CreateProduct(product)
+}
+func (s *CatalogService) UpdateProductService(product *model.CatalogProduct) error {
+	return s.Repo.UpdateProduct(product)
+}
+func (s *CatalogService) DeleteProductService(id int) error {
+	return s.Repo.DeleteProduct(id)
+}
+func (s *CatalogService) CreateCategoryService(category *model.Category) error {
+	return s.Repo.CreateCategory(category)
+}
+func (s *CatalogService) UpdateCategoryService(category *model.Category) error {
+	return s.Repo.UpdateCategory(category)
+}
+func (s *CatalogService) DeleteCategoryService(id int) error {
+	return s.Repo.DeleteCategory(id)
+}
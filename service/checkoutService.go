@@ -1,33 +1,109 @@
-package service
-
-import (
-	"ecommerce/model"
-	"ecommerce/repository"
-)
-
-type CheckoutService struct {
-	Repo repository.CheckoutRepository
-}
-
-func NewCheckoutService(repo repository.CheckoutRepository) CheckoutService {
-	return CheckoutService{Repo: repo}
-}
-
-func (s *CheckoutService) GetAllCartService(userID int) ([]*model.Checkout, error) {
-	return s.Repo.GetAllCart(userID)
-}
-func (s *CheckoutService) GetTotalCartService(userID int) (*model.Checkout, error) {
-	return s.Repo.GetTotalCart(userID)
-}
-func (s *CheckoutService)  AddCartService(cart model.Checkout) error {
-	return s.Repo.AddCart(cart)
-}
-func (s *CheckoutService)  DeleteCartService(id, userID int) error {
-	return s.Repo.DeleteCart(id, userID)
-}
-func (s *CheckoutService) UpdateCartService(userID, productID, quantity int) (*model.Checkout, error) {
-	return s.Repo.UpdateCart(userID, productID, quantity)
-}
-func (s *CheckoutService) CreateOrderService(userID int, productID []int, addressIndex int) (*model.OrderResponse, error) {
-	return s.Repo.CreateOrder(userID, productID, addressIndex)
-}
\ No newline at end of file
+package service
+
+import (
+	"ecommerce/model"
+	"ecommerce/repository"
+	"ecommerce/shipping"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+var (
+	ErrInvalidStatusTransition = errors.New("invalid order status transition")
+	ErrCartChanged             = errors.New("cart has changed since it was last viewed")
+)
+
+var orderStatusTransitions = map[string][]string{
+	model.OrderStatusPending:    {model.OrderStatusPaid, model.OrderStatusCancelled},
+	model.OrderStatusPaid:       {model.OrderStatusProcessing, model.OrderStatusRefunded},
+	model.OrderStatusProcessing: {model.OrderStatusShipped, model.OrderStatusRefunded},
+	model.OrderStatusShipped:    {model.OrderStatusDelivered},
+	model.OrderStatusDelivered:  {model.OrderStatusRefunded},
+}
+
+func CanTransitionOrderStatus(from, to string) bool {
+	for _, next := range orderStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+type CheckoutService struct {
+	Repo     repository.CheckoutRepository
+	Shipping shipping.ShippingCalculator
+}
+
+func NewCheckoutService(repo repository.CheckoutRepository, calculator shipping.ShippingCalculator) CheckoutService {
+	return CheckoutService{Repo: repo, Shipping: calculator}
+}
+
+// CreateOrderService places the order at current prices. The repository
+// reprices the selected lines inside the order transaction; if any changed or
+// became unavailable the order is refused with ErrCartChanged and the
+// notices, so the customer can confirm the new amounts (the cart already
+// carries them) and order again.
+func (s *CheckoutService) CreateOrderService(order model.CreateOrderRequest) (*model.OrderResponse, []model.CartNotice, error) {
+	order.CouponCode = strings.TrimSpace(order.CouponCode)
+	if order.ShippingMethod == "" {
+		order.ShippingMethod = shipping.DefaultMethod
+	}
+
+	summary, err := s.Repo.GetCheckoutSummary(order.UserID, order.ProductID, order.AddressID)
+	if err != nil {
+		return nil, nil, err
+	}
+	order.AddressID = summary.AddressID
+	quote, err := s.Shipping.Quote(order.ShippingMethod, shipping.Request{Address: summary.Address, Subtotal: summary.Subtotal, WeightGrams: summary.WeightGrams})
+	if err != nil {
+		return nil, nil, err
+	}
+
+	response, notices, err := s.Repo.CreateOrder(order, quote)
+	if err != nil {
+		return nil, nil, err
+	}
+	if len(notices) > 0 {
+		return nil, notices, ErrCartChanged
+	}
+	return response, nil, nil
+}
+func (s *CheckoutService) GetShippingMethodsService() []model.ShippingMethod {
+	return s.Shipping.Methods()
+}
+func (s *CheckoutService) GetShippingRatesService(userID int, productID []int, addressID int) ([]*model.ShippingQuote, error) {
+	summary, err := s.Repo.GetCheckoutSummary(userID, productID, addressID)
+	if err != nil {
+		return nil, err
+	}
+	return shipping.QuoteAll(s.Shipping, shipping.Request{Address: summary.Address, Subtotal: summary.Subtotal, WeightGrams: summary.WeightGrams})
+}
+func (s *CheckoutService) GetAllOrdersService(userID int, status string, startDate, endDate time.Time, page model.Pagination) ([]*model.OrderResponse, model.Pagination, error) {
+	return s.Repo.GetAllOrders(userID, status, startDate, endDate, page)
+}
+func (s *CheckoutService) GetOrderByIDService(id, userID int) (*model.OrderResponse, error) {
+	return s.Repo.GetOrderByID(id, userID)
+}
+func (s *CheckoutService) UpdateOrderStatusService(id, changedBy int, status, note string) error {
+	current, err := s.Repo.GetOrderStatus(id)
+	if err != nil {
+		return err
+	}
+	if !CanTransitionOrderStatus(current, status) {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidStatusTransition, current, status)
+	}
+	return s.Repo.UpdateOrderStatus(id, current, status, changedBy, note)
+}
+func (s *CheckoutService) CancelOrderService(id, userID int) error {
+	order, err := s.Repo.GetOrderByID(id, userID)
+	if err != nil {
+		return err
+	}
+	if order.Status != model.OrderStatusPending {
+		return fmt.Errorf("%w: only pending orders can be cancelled", ErrInvalidStatusTransition)
+	}
+	return s.Repo.UpdateOrderStatus(id, order.Status, model.OrderStatusCancelled, userID, "cancelled by customer")
+}
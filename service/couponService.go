@@ -0,0 +1,29 @@
+package service
+
+import (
+	"ecommerce/model"
+	"ecommerce/repository"
+	"strings"
+)
+
+type CouponService struct {
+	Repo repository.CouponRepository
+}
+
+func NewCouponService(repo repository.CouponRepository) CouponService {
+	return CouponService{Repo: repo}
+}
+
+func (s *CouponService) CreateCouponService(coupon *model.Coupon) error {
+	coupon.Code = strings.ToUpper(strings.TrimSpace(coupon.Code))
+	return s.Repo.CreateCoupon(coupon)
+}
+func (s *CouponService) GetAllCouponsService() ([]*model.Coupon, error) {
+	return s.Repo.GetAllCoupons()
+}
+func (s *CouponService) DeactivateCouponService(id int) error {
+	return s.Repo.DeactivateCoupon(id)
+}
+func (s *CouponService) ApplyCouponService(userID int, code string, productIDs []int) (*model.CouponPreview, error) {
+	return s.Repo.PreviewCoupon(userID, strings.TrimSpace(code), productIDs)
+}
@@ -1,42 +1,102 @@
-package service
-
-import (
-	"ecommerce/model"
-	"ecommerce/repository"
-)
-
-type HomePageService struct {
-	Repo repository.HomePageRepository
-}
-
-func NewHomePageService(repo repository.HomePageRepository) HomePageService {
-	return HomePageService{Repo: repo}
-}
-
-func (s *HomePageService) GetAllProductsService(name string, categoryID, limit, page int) ([]*model.Product, int, int, error) {
-	return s.Repo.GetAllProducts(name, categoryID, limit, page)
-}
-func (s *HomePageService) GetByIdProductService(id int) (*model.ProductID, error) {
-	return s.Repo.GetByIdProduct(id)
-}
-func (s *HomePageService) GetAllCategoriesService() ([]*model.Category, error) {
-	return s.Repo.GetAllCategories()
-}
-func (s *HomePageService) GetAllBannersService() ([]*model.BannerWeeklyPromotionRecomment, error) {
-	return s.Repo.GetAllBanners()
-}
-func (s *HomePageService) GetAllBestSellingProductsService(limit, page int) ([]*model.Product, int, int, error) {
-	return s.Repo.GetAllBestSellingProducts(limit, page)
-}
-func (s *HomePageService) GetAllWeeklyPromotionProductsService() ([]*model.BannerWeeklyPromotionRecomment, error) {
-	return s.Repo.GetAllWeeklyPromotionProducts()
-}
-func (s *HomePageService) GetAllRecommentsProductsService() ([]*model.BannerWeeklyPromotionRecomment, error) {
-	return s.Repo.GetAllRecommentsProducts()
-}
-func (s *HomePageService) AddWishlistService(wishlist model.Wishlist)  error {
-	return s.Repo.AddWishlist(wishlist)
-}
-func (s *HomePageService) DeleteWishlistService(id, userID int) error {
-    return s.Repo.DeleteWishlist(id, userID)
-}
\ No newline at end of file
+package service
+
+import (
+	"ecommerce/helper"
+	"ecommerce/model"
+	"ecommerce/repository"
+)
+
+const maxSearchSuggestions = 3
+
+type HomePageService struct {
+	Repo repository.HomePageRepository
+}
+
+func NewHomePageService(repo repository.HomePageRepository) HomePageService {
+	return HomePageService{Repo: repo}
+}
+
+func (s *HomePageService) GetAllProductsService(filter model.ProductFilter, page model.Pagination) ([]*model.Product, *model.ProductFacets, model.Pagination, error) {
+	products, page, err := s.Repo.GetAllProducts(filter, page)
+	if err != nil {
+		return nil, nil, page, err
+	}
+	facets, err := s.Repo.GetProductFacets(filter)
+	if err != nil {
+		return nil, nil, page, err
+	}
+	return products, facets, page, nil
+}
+
+// SearchProductsService offers "did you mean" suggestions when nothing
+// matched the query as typed, i.e. every hit came from the typo fallback.
+// Full-text matches rank first, so page 1 answers that from its top hit;
+// later pages ask the repository, since they may hold only fallback hits.
+func (s *HomePageService) SearchProductsService(query string, categoryID, limit, page int) (*model.ProductSearch, int, int, error) {
+	items, totalItems, totalPages, err := s.Repo.SearchProducts(query, categoryID, limit, page)
+	if err != nil {
+		return nil, 0, 0, err
+	}
+
+	matched := len(items) > 0 && items[0].Relevance > 0
+	if page > 1 && !matched {
+		matched, err = s.Repo.HasSearchMatch(query, categoryID)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+	}
+
+	result := &model.ProductSearch{Query: query, Items: items}
+	if !matched {
+		suggestions, err := s.Repo.GetSearchSuggestions(query, maxSearchSuggestions)
+		if err != nil {
+			return nil, 0, 0, err
+		}
+		result.Suggestions = suggestions
+	}
+	return result, totalItems, totalPages, nil
+}
+func (s *HomePageService) GetByIdProductService(id int) (*model.ProductID, error) {
+	return s.Repo.GetByIdProduct(id)
+}
+func (s *HomePageService) GetAllCategoriesService() ([]*model.Category, error) {
+	return s.Repo.GetAllCategories()
+}
+func (s *HomePageService) GetAllBannersService() ([]*model.BannerWeeklyPromotionRecomment, error) {
+	return s.Repo.GetAllBanners()
+}
+func (s *HomePageService) GetAllBestSellingProductsService(page model.Pagination) ([]*model.Product, model.Pagination, error) {
+	return s.Repo.GetAllBestSellingProducts(page)
+}
+func (s *HomePageService) GetAllWeeklyPromotionProductsService() ([]*model.BannerWeeklyPromotionRecomment, error) {
+	return s.Repo.GetAllWeeklyPromotionProducts()
+}
+func (s *HomePageService) GetAllRecommentsProductsService() ([]*model.BannerWeeklyPromotionRecomment, error) {
+	return s.Repo.GetAllRecommentsProducts()
+}
+func (s *HomePageService) AddWishlistService(wishlist model.Wishlist) error {
+	return s.Repo.AddWishlist(wishlist)
+}
+func (s *HomePageService) DeleteWishlistService(id, userID int) error {
+	return s.Repo.DeleteWishlist(id, userID)
+}
+func (s *HomePageService) GetAllWishlistService(userID int) ([]*model.WishlistItem, error) {
+	return s.Repo.GetAllWishlist(userID)
+}
+func (s *HomePageService) CreateWishlistShareService(userID int) (*model.WishlistShare, error) {
+	token, err := helper.GenerateToken()
+	if err != nil {
+		return nil, err
+	}
+	token, err = s.Repo.CreateWishlistShare(userID, token)
+	if err != nil {
+		return nil, err
+	}
+	return &model.WishlistShare{Token: token}, nil
+}
+func (s *HomePageService) DeleteWishlistShareService(userID int) error {
+	return s.Repo.DeleteWishlistShare(userID)
+}
+func (s *HomePageService) GetSharedWishlistService(token string) (*model.SharedWishlist, error) {
+	return s.Repo.GetSharedWishlist(token)
+}
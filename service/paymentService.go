@@ -0,0 +1,206 @@
+package service
+
+import (
+	"context"
+	"ecommerce/model"
+	"ecommerce/payment"
+	"ecommerce/repository"
+	"errors"
+	"fmt"
+	"math"
+)
+
+var (
+	ErrOrderNotPayable          = errors.New("only pending orders can be paid")
+	ErrPaymentNotRefundable     = errors.New("only succeeded payments can be refunded")
+	ErrInvalidPaymentTransition = errors.New("invalid payment status transition")
+	ErrPaymentAmountMismatch    = errors.New("payment amount does not match the payment")
+)
+
+// paymentStatusTransitions only moves payments forward, so a late or
+// replayed webhook cannot turn a paid payment into a failed one or a
+// refunded payment back into a paid one. A failed payment may still succeed
+// when the customer retries on the same intent.
+var paymentStatusTransitions = map[string][]string{
+	model.PaymentStatusPending:    {model.PaymentStatusAuthorized, model.PaymentStatusSucceeded, model.PaymentStatusFailed},
+	model.PaymentStatusAuthorized: {model.PaymentStatusSucceeded, model.PaymentStatusFailed},
+	model.PaymentStatusFailed:     {model.PaymentStatusSucceeded},
+	model.PaymentStatusSucceeded:  {model.PaymentStatusRefunded},
+}
+
+func CanTransitionPaymentStatus(from, to string) bool {
+	for _, next := range paymentStatusTransitions[from] {
+		if next == to {
+			return true
+		}
+	}
+	return false
+}
+
+// paymentStatusesBefore lists the statuses a payment may move to status
+// from, which the repository uses to guard the update.
+func paymentStatusesBefore(status string) []string {
+	var from []string
+	for current := range paymentStatusTransitions {
+		if CanTransitionPaymentStatus(current, status) {
+			from = append(from, current)
+		}
+	}
+	return from
+}
+
+type PaymentService struct {
+	Repo     repository.PaymentRepository
+	Orders   repository.CheckoutRepository
+	Provider payment.PaymentProvider
+}
+
+func NewPaymentService(repo repository.PaymentRepository, orders repository.CheckoutRepository, provider payment.PaymentProvider) PaymentService {
+	return PaymentService{Repo: repo, Orders: orders, Provider: provider}
+}
+
+func (s *PaymentService) CreatePaymentService(orderID, userID int) (*model.Payment, error) {
+	order, err := s.Orders.GetOrderByID(orderID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if order.Status != model.OrderStatusPending {
+		return nil, ErrOrderNotPayable
+	}
+
+	// Paying twice for the same order reuses the open intent.
+	existing, err := s.Repo.GetLatestPayment(orderID)
+	if err == nil && (existing.Status == model.PaymentStatusPending || existing.Status == model.PaymentStatusAuthorized) {
+		return existing, nil
+	}
+	if err != nil && err.Error() != "payment not found" {
+		return nil, err
+	}
+
+	intent, err := s.Provider.CreateIntent(context.Background(), payment.Intent{OrderID: orderID, Amount: order.TotalAmount, Currency: "IDR"})
+	if err != nil {
+		return nil, fmt.Errorf("failed to create payment intent: %w", err)
+	}
+
+	result := &model.Payment{
+		OrderID:     orderID,
+		Provider:    s.Provider.Name(),
+		ProviderRef: intent.ProviderRef,
+		Amount:      order.TotalAmount,
+	}
+	if err := s.Repo.CreatePayment(result); err != nil {
+		return nil, err
+	}
+	result.ClientSecret = intent.ClientSecret
+	result.RedirectURL = intent.RedirectURL
+
+	return result, nil
+}
+
+func (s *PaymentService) GetPaymentService(orderID, userID int) (*model.Payment, error) {
+	if _, err := s.Orders.GetOrderByID(orderID, userID); err != nil {
+		return nil, err
+	}
+	return s.Repo.GetLatestPayment(orderID)
+}
+
+// HandleWebhookService drops replayed events and events that would move the
+// payment backwards before anything is captured, so a redelivered
+// payment.authorized never captures twice.
+func (s *PaymentService) HandleWebhookService(payload []byte, signature string) error {
+	event, err := s.Provider.VerifyWebhook(payload, signature)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.Repo.GetPaymentByRef(s.Provider.Name(), event.ProviderRef)
+	if err != nil {
+		return err
+	}
+
+	processed, err := s.Repo.PaymentEventExists(result.Provider, event.ID)
+	if err != nil {
+		return err
+	}
+	if processed {
+		return repository.ErrDuplicatePaymentEvent
+	}
+
+	var paymentStatus, orderStatus string
+	switch event.Type {
+	case payment.EventAuthorized, payment.EventSucceeded:
+		paymentStatus, orderStatus = model.PaymentStatusSucceeded, model.OrderStatusPaid
+	case payment.EventFailed:
+		paymentStatus = model.PaymentStatusFailed
+	case payment.EventRefunded:
+		paymentStatus, orderStatus = model.PaymentStatusRefunded, model.OrderStatusRefunded
+	default:
+		return nil
+	}
+
+	if !CanTransitionPaymentStatus(result.Status, paymentStatus) {
+		return fmt.Errorf("%w from %s to %s", ErrInvalidPaymentTransition, result.Status, paymentStatus)
+	}
+	if paymentStatus != model.PaymentStatusFailed && math.Abs(event.Amount-result.Amount) >= 0.005 {
+		return fmt.Errorf("%w: event %.2f, payment %.2f", ErrPaymentAmountMismatch, event.Amount, result.Amount)
+	}
+
+	if event.Type == payment.EventAuthorized {
+		if err := s.Provider.Capture(context.Background(), result.ProviderRef, result.Amount); err != nil {
+			return fmt.Errorf("failed to capture payment: %w", err)
+		}
+	}
+
+	return s.applyPaymentStatus(result, event.ID, event.Type, paymentStatus, orderStatus, 0)
+}
+
+func (s *PaymentService) RefundPaymentService(orderID, changedBy int) (*model.Payment, error) {
+	result, err := s.Repo.GetLatestPayment(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransitionPaymentStatus(result.Status, model.PaymentStatusRefunded) {
+		return nil, ErrPaymentNotRefundable
+	}
+
+	current, err := s.Orders.GetOrderStatus(orderID)
+	if err != nil {
+		return nil, err
+	}
+	if !CanTransitionOrderStatus(current, model.OrderStatusRefunded) {
+		return nil, fmt.Errorf("%w from %s to %s", ErrInvalidStatusTransition, current, model.OrderStatusRefunded)
+	}
+
+	if err := s.Provider.Refund(context.Background(), result.ProviderRef, result.Amount); err != nil {
+		return nil, fmt.Errorf("failed to refund payment: %w", err)
+	}
+
+	if err := s.applyPaymentStatus(result, "", payment.EventRefunded, model.PaymentStatusRefunded, model.OrderStatusRefunded, changedBy); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// applyPaymentStatus only moves the order when the lifecycle allows it. A
+// success for an order that can no longer be paid, e.g. one cancelled while
+// the customer was paying, is refunded straight away and recorded as
+// refunded, so the customer is never charged for an order they won't get.
+func (s *PaymentService) applyPaymentStatus(result *model.Payment, eventID, eventType, paymentStatus, orderStatus string, changedBy int) error {
+	current, err := s.Orders.GetOrderStatus(result.OrderID)
+	if err != nil {
+		return err
+	}
+
+	fromStatuses := paymentStatusesBefore(paymentStatus)
+	toOrderStatus := ""
+	if orderStatus != "" && CanTransitionOrderStatus(current, orderStatus) {
+		toOrderStatus = orderStatus
+	} else if orderStatus == model.OrderStatusPaid {
+		if err := s.Provider.Refund(context.Background(), result.ProviderRef, result.Amount); err != nil {
+			return fmt.Errorf("failed to refund payment for %s order: %w", current, err)
+		}
+		paymentStatus = model.PaymentStatusRefunded
+	}
+
+	return s.Repo.ApplyPaymentEvent(result, eventID, eventType, fromStatuses, paymentStatus, current, toOrderStatus, changedBy)
+}
@@ -0,0 +1,42 @@
+package service
+
+import (
+	"ecommerce/model"
+	"ecommerce/repository"
+	"math"
+	"strings"
+	"time"
+)
+
+type PricingService struct {
+	Repo repository.PricingRepository
+}
+
+func NewPricingService(repo repository.PricingRepository) PricingService {
+	return PricingService{Repo: repo}
+}
+
+// QuoteService prices quantity units of a product at the given moment. The
+// promotion or bulk tier, whichever is larger, sets the unit price; a coupon,
+// when given, is then applied to the line as it would be at checkout.
+func (s *PricingService) QuoteService(userID, productID, quantity int, at time.Time, couponCode string) (*model.PriceQuote, error) {
+	quote, err := s.Repo.GetPrice(productID, quantity, at)
+	if err != nil {
+		return nil, err
+	}
+
+	quote.Subtotal = math.Round(quote.UnitPrice*float64(quantity)*100) / 100
+	quote.Total = quote.Subtotal
+
+	couponCode = strings.TrimSpace(couponCode)
+	if couponCode != "" {
+		preview, err := s.Repo.PreviewCoupon(userID, couponCode, productID, quote.Subtotal, at)
+		if err != nil {
+			return nil, err
+		}
+		quote.Coupon = preview
+		quote.Total = preview.Total
+	}
+
+	return quote, nil
+}
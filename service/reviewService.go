@@ -0,0 +1,27 @@
+package service
+
+import (
+	"ecommerce/model"
+	"ecommerce/repository"
+)
+
+type ReviewService struct {
+	Repo repository.ReviewRepository
+}
+
+func NewReviewService(repo repository.ReviewRepository) ReviewService {
+	return ReviewService{Repo: repo}
+}
+
+func (s *ReviewService) CreateReviewService(review *model.Review) error {
+	return s.Repo.CreateReview(review)
+}
+func (s *ReviewService) GetReviewsByProductService(productID, limit, page int) (*model.ProductReviews, int, int, error) {
+	return s.Repo.GetReviewsByProduct(productID, limit, page)
+}
+func (s *ReviewService) UpdateReviewService(review model.Review) (*model.Review, error) {
+	return s.Repo.UpdateReview(review)
+}
+func (s *ReviewService) DeleteReviewService(id, userID int) error {
+	return s.Repo.DeleteReview(id, userID)
+}
@@ -1,60 +1,563 @@
-package service
-
-import (
-	"ecommerce/model"
-	"ecommerce/repository"
-	"errors"
-	"time"
-)
-
-type AuthService struct {
-	RepoUser repository.AuthRepository
-}
-
-func NewAuthService(repoUser repository.AuthRepository) AuthService {
-	return AuthService{repoUser}
-}
-
-func (us *AuthService) LoginService(user model.User) (*model.User, error) {
-	return us.RepoUser.GetUserLogin(user)
-}
-
-func (s *AuthService) RegisterService(user model.User) error {
-	return s.RepoUser.Create(&user)
-}
-func (s *AuthService) CreateSessionService(user model.Session) error {
-	return s.RepoUser.CreateSession(&user)
-}
-
-func (s *AuthService) Logout(token string) error {
-	return s.RepoUser.DeleteSession(token)
-}
-func (s *AuthService) GetAllAddressService(id int) ([]*model.User, error) {
-	return s.RepoUser.GetAllAddress(id)
-}
-func (s *AuthService) GetDetailUserService(id int) (*model.User, error) {
-	return s.RepoUser.GetDetailUser(id)
-}
-func (s *AuthService) UpdateUserService(userID int, name, email, password, newPassword string) (*model.User, error) {
-	return s.RepoUser.UpdateUser(userID, name, email, password, newPassword)
-}
-func (s *AuthService) UpdateAddressUserService(userID int, address []string) (*model.User, error) {
-	return s.RepoUser.UpdateAddressUser(userID, address)
-}
-func (s *AuthService) SetDefaultAddressService(userID int, addressIndex int) (*model.User, error) {
-	return s.RepoUser.SetDefaultAddress(userID, addressIndex)
-}
-func (s *AuthService) DeleteAddressService(userID int, addressIndex int) (*model.User, error) {
-	return s.RepoUser.DeleteAddress(userID, addressIndex)
-}
-func (s *AuthService) CreateAddressService(userID int, newAddress string) (*model.User, error) {
-	return s.RepoUser.CreateAddress(userID, newAddress)
-}
-
-func (s *AuthService) VerifyToken(token string) (int, error) {
-	session, err := s.RepoUser.GetSessionByToken(token)
-	if err != nil || session == nil || session.ExpiresAt.Before(time.Now()) {
-		return 0, errors.New("invalid or expired token")
-	}
-	return session.UserID, nil
-}
+package service
+
+import (
+	"context"
+	"ecommerce/helper"
+	"ecommerce/jwt"
+	"ecommerce/model"
+	"ecommerce/notify"
+	"ecommerce/repository"
+	"ecommerce/util"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	verificationCodeTTL     = 15 * time.Minute
+	maxVerificationAttempts = 5
+	codeRequestWindow       = time.Hour
+	maxCodeRequests         = 3
+	// maxBackoffShift bounds the login backoff doubling; 2^30 times any
+	// sensible backoff is already past any sensible lockout.
+	maxBackoffShift = 30
+)
+
+var (
+	ErrAlreadyVerified     = errors.New("contact is already verified")
+	ErrInvalidCredentials  = errors.New("invalid email/phone or password")
+	ErrTooManyCodeRequests = errors.New("too many codes requested, try again later")
+)
+
+// LoginLockedError is returned while the login identifier or the client IP
+// is backing off or locked out after repeated failures.
+type LoginLockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LoginLockedError) Error() string {
+	return "too many failed login attempts"
+}
+
+// dummyPasswordHash is checked when no account matches, so an unknown
+// identifier takes as long to reject as a wrong password.
+var dummyPasswordHash, _ = helper.HashPassword("not-a-real-password")
+
+// AuthService verifies access tokens against the sessions table, or, when
+// JWT is set, statelessly against the token signature and an in-memory copy
+// of the revoked sessions.
+type AuthService struct {
+	RepoUser repository.AuthRepository
+	JWT      *jwt.Manager
+	Notifier notify.Notifier
+	Config   util.AuthConfig
+	revoked  *revocationList
+}
+
+func NewAuthService(repoUser repository.AuthRepository, jwtManager *jwt.Manager, notifier notify.Notifier, config util.Configuration) AuthService {
+	return AuthService{RepoUser: repoUser, JWT: jwtManager, Notifier: notifier, Config: config.Auth, revoked: &revocationList{}}
+}
+
+// LoginService checks the credentials of a login from ip. Failures are
+// counted per identifier and per IP; every failure looks the same to the
+// caller whether or not the account exists.
+func (us *AuthService) LoginService(user model.User, ip string) (*model.User, error) {
+	accountKey := loginKey(user)
+	blockedUntil, err := us.RepoUser.GetLoginBlockedUntil(accountKey, ip)
+	if err != nil {
+		return nil, err
+	}
+	if blockedUntil != nil {
+		return nil, &LoginLockedError{RetryAfter: time.Until(*blockedUntil)}
+	}
+
+	found, err := us.RepoUser.GetUserLogin(user)
+	if err != nil && err.Error() != "invalid username or password" {
+		return nil, err
+	}
+
+	var match, legacy bool
+	if found != nil {
+		match, legacy = helper.CheckPassword(found.Password, user.Password)
+	} else {
+		helper.CheckPassword(dummyPasswordHash, user.Password)
+	}
+	if !match {
+		if err := us.recordLoginFailure(found, accountKey, ip); err != nil {
+			return nil, err
+		}
+		return nil, ErrInvalidCredentials
+	}
+
+	if err := us.RepoUser.ClearLoginFailures(model.LoginScopeAccount, accountKey); err != nil {
+		return nil, err
+	}
+
+	// Rows created before passwords were hashed are upgraded on first login.
+	if legacy {
+		hash, err := helper.HashPassword(user.Password)
+		if err != nil {
+			return nil, err
+		}
+		if err := us.RepoUser.UpdatePassword(found.ID, hash); err != nil {
+			return nil, err
+		}
+	}
+
+	found.Password = ""
+	return found, nil
+}
+
+// recordLoginFailure counts a failure against the identifier and the IP. The
+// first half of the allowed failures are free, each one after that doubles
+// the wait before the next attempt, and reaching the limit locks the login
+// out and writes an audit entry.
+func (s *AuthService) recordLoginFailure(found *model.User, accountKey, ip string) error {
+	now := time.Now()
+	limits := []struct {
+		scope string
+		key   string
+		max   int
+	}{
+		{model.LoginScopeAccount, accountKey, s.Config.LoginMaxFailures},
+		{model.LoginScopeIP, ip, s.Config.LoginMaxIPFailures},
+	}
+
+	for _, limit := range limits {
+		failures, err := s.RepoUser.RecordLoginFailure(limit.scope, limit.key, now.Add(-s.Config.LoginLockout))
+		if err != nil {
+			return err
+		}
+
+		free := limit.max / 2
+		if failures <= free {
+			continue
+		}
+		locked := failures >= limit.max
+		wait := s.Config.LoginLockout
+		if !locked {
+			// A large shift overflows the duration, possibly to a negative
+			// wait; the shift is capped and anything out of range locks.
+			wait = s.Config.LoginBackoff << min(failures-free-1, maxBackoffShift)
+			if wait <= 0 || wait > s.Config.LoginLockout {
+				wait = s.Config.LoginLockout
+			}
+		}
+		if err := s.RepoUser.BlockLogin(limit.scope, limit.key, now.Add(wait)); err != nil {
+			return err
+		}
+		// Only the attempt that reaches the limit is audited; later ones are
+		// rejected before they are counted.
+		if failures != limit.max {
+			continue
+		}
+
+		entry := &model.AuditLog{
+			Event:      model.AuditLoginLocked,
+			Identifier: accountKey,
+			IPAddress:  ip,
+			Detail: map[string]interface{}{
+				"scope":        limit.scope,
+				"failures":     failures,
+				"locked_until": now.Add(wait),
+			},
+		}
+		if found != nil {
+			entry.UserID = &found.ID
+		}
+		if err := s.RepoUser.CreateAuditLog(entry); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// loginKey is the identifier failed logins are counted against.
+func loginKey(user model.User) string {
+	if user.Email != "" {
+		return "email:" + strings.ToLower(strings.TrimSpace(user.Email))
+	}
+	return "phone:" + strings.TrimSpace(user.Phone)
+}
+
+func (s *AuthService) RegisterService(user model.User) error {
+	hash, err := helper.HashPassword(user.Password)
+	if err != nil {
+		return err
+	}
+	user.Password = hash
+	return s.RepoUser.Create(&user)
+}
+func (s *AuthService) CreateSessionService(session *model.Session) error {
+	session.TokenHash = helper.HashToken(session.Token)
+	session.RefreshHash = helper.HashToken(session.RefreshToken)
+	if err := s.RepoUser.CreateSession(session); err != nil {
+		return err
+	}
+	return s.issueJWT(session)
+}
+func (s *AuthService) RefreshSessionService(refreshToken string, session *model.Session) error {
+	session.TokenHash = helper.HashToken(session.Token)
+	session.RefreshHash = helper.HashToken(session.RefreshToken)
+	err := s.RepoUser.RotateRefreshToken(helper.HashToken(refreshToken), session)
+	if errors.Is(err, repository.ErrRefreshTokenReused) {
+		s.syncRevocations()
+	}
+	if err != nil {
+		return err
+	}
+	return s.issueJWT(session)
+}
+
+// issueJWT replaces the opaque access token with a signed JWT in JWT mode.
+// Revocation is keyed on the sid claim rather than the jti: the session id
+// survives refreshes, so revoking a session also rejects the access tokens
+// issued before its last refresh.
+func (s *AuthService) issueJWT(session *model.Session) error {
+	if s.JWT == nil {
+		return nil
+	}
+	token, err := s.JWT.Sign(jwt.Claims{
+		Subject:   strconv.Itoa(session.UserID),
+		Role:      session.Role,
+		SessionID: session.ID,
+		ID:        session.Token,
+		IssuedAt:  time.Now().Unix(),
+		ExpiresAt: session.ExpiresAt.Unix(),
+	})
+	if err != nil {
+		return err
+	}
+	session.Token = token
+	return nil
+}
+func (s *AuthService) GetSessionsService(userID, currentSessionID int) ([]*model.Session, error) {
+	sessions, err := s.RepoUser.GetActiveSessions(userID)
+	if err != nil {
+		return nil, err
+	}
+	for _, session := range sessions {
+		session.Current = session.ID == currentSessionID
+	}
+	return sessions, nil
+}
+func (s *AuthService) RevokeSessionService(userID, sessionID int) error {
+	if err := s.RepoUser.RevokeSession(userID, sessionID); err != nil {
+		return err
+	}
+	s.syncRevocations()
+	return nil
+}
+func (s *AuthService) RevokeAllSessionsService(userID int) (int64, error) {
+	revoked, err := s.RepoUser.RevokeAllSessions(userID)
+	if err != nil {
+		return 0, err
+	}
+	s.syncRevocations()
+	return revoked, nil
+}
+
+// PurgeSessionsEvery removes expired and revoked sessions on every tick
+// until ctx is done. Failures are passed to report and retried on the next
+// tick. It blocks, so callers start it in its own goroutine.
+func (s *AuthService) PurgeSessionsEvery(ctx context.Context, interval time.Duration, report func(error)) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if _, err := s.RepoUser.PurgeExpiredSessions(); err != nil {
+				report(err)
+			}
+		}
+	}
+}
+
+// SyncRevocationsEvery reloads the revocation list until ctx is done, so
+// sessions revoked through other instances are rejected here too. It returns
+// at once outside JWT mode and blocks otherwise.
+func (s *AuthService) SyncRevocationsEvery(ctx context.Context, interval time.Duration) {
+	if s.JWT == nil {
+		return
+	}
+	s.syncRevocations()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.syncRevocations()
+		}
+	}
+}
+
+// syncRevocations keeps the previous list when the reload fails; the
+// repository logs the error.
+func (s *AuthService) syncRevocations() {
+	if s.JWT == nil {
+		return
+	}
+	if revoked, err := s.RepoUser.GetRevokedSessionIDs(); err == nil {
+		s.revoked.replace(revoked)
+	}
+}
+
+func (s *AuthService) Logout(token string) error {
+	if s.JWT != nil {
+		session, err := s.verifyJWT(token)
+		if err != nil {
+			return err
+		}
+		return s.RevokeSessionService(session.UserID, session.ID)
+	}
+
+	return s.RepoUser.RevokeSessionByTokenHash(helper.HashToken(token))
+}
+
+// RequestPasswordResetService sends a reset code to the contact stored on
+// the account matching the given email (or phone when no email is given).
+// Unknown contacts and throttled requests are ignored without an error so
+// the endpoint cannot be used to find out who has an account.
+func (s *AuthService) RequestPasswordResetService(email, phone string) error {
+	user, err := s.RepoUser.GetUserLogin(model.User{Email: email, Phone: phone})
+	if err != nil {
+		return nil
+	}
+
+	channel, to := notify.ChannelEmail, user.Email
+	if email == "" {
+		channel, to = notify.ChannelSMS, user.Phone
+	}
+	err = s.sendCode(user.ID, model.PurposeResetPassword, notify.Message{
+		Channel: channel,
+		To:      to,
+		Subject: "Reset your password",
+		Body:    "Your password reset code is %s. It expires in %d minutes. If you did not ask for it, ignore this message.",
+	})
+	if errors.Is(err, ErrTooManyCodeRequests) {
+		return nil
+	}
+	return err
+}
+
+// ResetPasswordService sets a new password with a reset code and logs the
+// user out everywhere.
+func (s *AuthService) ResetPasswordService(email, phone, code, newPassword string) error {
+	user, err := s.RepoUser.GetUserLogin(model.User{Email: email, Phone: phone})
+	if err != nil {
+		return repository.ErrVerificationCodeInvalid
+	}
+	if err := s.RepoUser.ConsumeVerificationCode(user.ID, model.PurposeResetPassword, helper.HashToken(code), maxVerificationAttempts); err != nil {
+		return err
+	}
+
+	hash, err := helper.HashPassword(newPassword)
+	if err != nil {
+		return err
+	}
+	if err := s.RepoUser.UpdatePassword(user.ID, hash); err != nil {
+		return err
+	}
+	_, err = s.RevokeAllSessionsService(user.ID)
+	return err
+}
+
+func (s *AuthService) RequestVerificationService(userID int, channel string) error {
+	user, err := s.RepoUser.GetDetailUser(userID)
+	if err != nil {
+		return err
+	}
+
+	switch channel {
+	case notify.ChannelEmail:
+		if user.Email == "" {
+			return fmt.Errorf("no email on account")
+		}
+		if *user.EmailVerified {
+			return ErrAlreadyVerified
+		}
+		return s.sendCode(userID, model.PurposeVerifyEmail, notify.Message{
+			Channel: notify.ChannelEmail,
+			To:      user.Email,
+			Subject: "Verify your email",
+			Body:    "Your verification code is %s. It expires in %d minutes.",
+		})
+	case notify.ChannelSMS:
+		if user.Phone == "" {
+			return fmt.Errorf("no phone on account")
+		}
+		if *user.PhoneVerified {
+			return ErrAlreadyVerified
+		}
+		return s.sendCode(userID, model.PurposeVerifyPhone, notify.Message{
+			Channel: notify.ChannelSMS,
+			To:      user.Phone,
+			Body:    "Your verification code is %s. It expires in %d minutes.",
+		})
+	default:
+		return fmt.Errorf("invalid channel")
+	}
+}
+
+func (s *AuthService) ConfirmVerificationService(userID int, channel, code string) error {
+	purpose := model.PurposeVerifyEmail
+	switch channel {
+	case notify.ChannelEmail:
+	case notify.ChannelSMS:
+		purpose = model.PurposeVerifyPhone
+	default:
+		return fmt.Errorf("invalid channel")
+	}
+
+	if err := s.RepoUser.ConsumeVerificationCode(userID, purpose, helper.HashToken(code), maxVerificationAttempts); err != nil {
+		return err
+	}
+	return s.RepoUser.MarkContactVerified(userID, purpose)
+}
+
+// sendCode stores a fresh code for purpose and sends it; msg.Body is a
+// format string receiving the code and its lifetime in minutes.
+func (s *AuthService) sendCode(userID int, purpose string, msg notify.Message) error {
+	// Every new code comes with a fresh attempt budget, so how often codes
+	// are issued is capped per account and per contact.
+	windowStart := time.Now().Add(-codeRequestWindow)
+	limits := []struct{ scope, key string }{
+		{model.CodeScopeUser, strconv.Itoa(userID)},
+		{model.CodeScopeContact, strings.ToLower(strings.TrimSpace(msg.To))},
+	}
+	for _, limit := range limits {
+		requests, err := s.RepoUser.RecordCodeRequest(limit.scope, limit.key, windowStart)
+		if err != nil {
+			return err
+		}
+		if requests > maxCodeRequests {
+			return ErrTooManyCodeRequests
+		}
+	}
+
+	code, err := helper.GenerateCode(6)
+	if err != nil {
+		return err
+	}
+	if err := s.RepoUser.CreateVerificationCode(userID, purpose, helper.HashToken(code), time.Now().Add(verificationCodeTTL)); err != nil {
+		return err
+	}
+
+	msg.Body = fmt.Sprintf(msg.Body, code, int(verificationCodeTTL.Minutes()))
+	return s.Notifier.Send(context.Background(), msg)
+}
+
+func (s *AuthService) GetAllAddressService(userID int) ([]*model.Address, error) {
+	return s.RepoUser.GetAllAddress(userID)
+}
+func (s *AuthService) GetDetailUserService(id int) (*model.User, error) {
+	return s.RepoUser.GetDetailUser(id)
+}
+func (s *AuthService) UpdateUserService(userID int, name, email, password, newPassword string) (*model.User, error) {
+	stored, err := s.RepoUser.GetPassword(userID)
+	if err != nil {
+		return nil, err
+	}
+
+	if match, _ := helper.CheckPassword(stored, password); !match {
+		return nil, fmt.Errorf("incorrect old password")
+	}
+
+	if newPassword == "" {
+		newPassword = password
+	}
+	hash, err := helper.HashPassword(newPassword)
+	if err != nil {
+		return nil, err
+	}
+
+	return s.RepoUser.UpdateUser(userID, name, email, hash)
+}
+func (s *AuthService) CreateAddressService(address model.Address) (*model.Address, error) {
+	if err := s.RepoUser.CreateAddress(&address); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+func (s *AuthService) UpdateAddressService(address model.Address) (*model.Address, error) {
+	if err := s.RepoUser.UpdateAddress(&address); err != nil {
+		return nil, err
+	}
+	return &address, nil
+}
+func (s *AuthService) SetDefaultAddressService(userID int, addressID int) (*model.User, error) {
+	return s.RepoUser.SetDefaultAddress(userID, addressID)
+}
+func (s *AuthService) DeleteAddressService(userID int, addressID int) error {
+	return s.RepoUser.DeleteAddress(userID, addressID)
+}
+
+func (s *AuthService) UpdateRoleService(userID int, role string) (*model.User, error) {
+	switch role {
+	case model.RoleCustomer, model.RoleStaff, model.RoleAdmin:
+	default:
+		return nil, fmt.Errorf("invalid role")
+	}
+	return s.RepoUser.UpdateRole(userID, role)
+}
+
+func (s *AuthService) VerifyToken(token string) (*model.Session, error) {
+	if s.JWT != nil {
+		return s.verifyJWT(token)
+	}
+
+	// Only token hashes are stored, so a leaked sessions table cannot be
+	// replayed as bearer tokens.
+	session, err := s.RepoUser.GetSessionByTokenHash(helper.HashToken(token))
+	if err != nil || session == nil || session.ExpiresAt.Before(time.Now()) {
+		return nil, errors.New("invalid or expired token")
+	}
+	// Activity tracking is best effort; the repository logs failures.
+	s.RepoUser.TouchSession(session.ID)
+	return session, nil
+}
+
+func (s *AuthService) verifyJWT(token string) (*model.Session, error) {
+	claims, err := s.JWT.Verify(token)
+	if err != nil || claims.SessionID == 0 || s.revoked.contains(claims.SessionID) {
+		return nil, errors.New("invalid or expired token")
+	}
+	userID, err := strconv.Atoi(claims.Subject)
+	if err != nil {
+		return nil, errors.New("invalid or expired token")
+	}
+
+	return &model.Session{
+		ID:        claims.SessionID,
+		UserID:    userID,
+		Role:      claims.Role,
+		ExpiresAt: time.Unix(claims.ExpiresAt, 0),
+	}, nil
+}
+
+// revocationList holds the ids of revoked sessions whose access tokens have
+// not expired yet.
+type revocationList struct {
+	mu       sync.RWMutex
+	sessions map[int]time.Time
+}
+
+func (l *revocationList) replace(sessions map[int]time.Time) {
+	l.mu.Lock()
+	l.sessions = sessions
+	l.mu.Unlock()
+}
+
+func (l *revocationList) contains(sessionID int) bool {
+	l.mu.RLock()
+	defer l.mu.RUnlock()
+	_, found := l.sessions[sessionID]
+	return found
+}
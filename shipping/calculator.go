@@ -0,0 +1,40 @@
+package shipping
+
+import (
+	"ecommerce/model"
+	"errors"
+)
+
+var (
+	ErrUnknownMethod      = errors.New("unknown shipping method")
+	ErrMethodNotAvailable = errors.New("shipping method is not available for this address")
+	DefaultMethod         = "regular"
+)
+
+type Request struct {
+	Address     string
+	Subtotal    float64
+	WeightGrams int
+}
+
+type ShippingCalculator interface {
+	Methods() []model.ShippingMethod
+	Quote(method string, req Request) (*model.ShippingQuote, error)
+}
+
+// QuoteAll prices every method the calculator offers for req, skipping the
+// ones that do not serve the destination.
+func QuoteAll(calculator ShippingCalculator, req Request) ([]*model.ShippingQuote, error) {
+	var quotes []*model.ShippingQuote
+	for _, method := range calculator.Methods() {
+		quote, err := calculator.Quote(method.Code, req)
+		if errors.Is(err, ErrMethodNotAvailable) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		quotes = append(quotes, quote)
+	}
+	return quotes, nil
+}
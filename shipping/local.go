@@ -0,0 +1,151 @@
+package shipping
+
+import (
+	"ecommerce/model"
+	"ecommerce/util"
+	"math"
+	"strings"
+	"unicode"
+)
+
+const (
+	RegionJawa        = "jawa"
+	RegionBaliNusa    = "bali_nusa_tenggara"
+	RegionSumatera    = "sumatera"
+	RegionKalimantan  = "kalimantan"
+	RegionSulawesi    = "sulawesi"
+	RegionMalukuPapua = "maluku_papua"
+	RegionOther       = "other"
+)
+
+type regionKeywords struct {
+	region   string
+	keywords []string
+}
+
+// cityRegions is checked before provinceRegions: street names often borrow
+// province names ("Jl. Sumatera, Surabaya"), so a city in the address is the
+// better signal. Keywords match whole words only, so "Solok" is not "solo"
+// and "Balikpapan" is not "bali".
+var cityRegions = []regionKeywords{
+	{RegionJawa, []string{"jakarta", "yogyakarta", "jogja", "bandung", "bekasi", "bogor", "depok", "tangerang", "semarang", "surabaya", "malang", "solo", "surakarta", "cirebon"}},
+	{RegionBaliNusa, []string{"denpasar", "lombok", "mataram", "kupang"}},
+	{RegionSumatera, []string{"medan", "padang", "pekanbaru", "jambi", "palembang", "bengkulu", "batam"}},
+	{RegionKalimantan, []string{"pontianak", "banjarmasin", "balikpapan", "samarinda", "palangka raya", "palangkaraya"}},
+	{RegionSulawesi, []string{"makassar", "manado", "gorontalo", "palu", "kendari", "mamuju"}},
+	{RegionMalukuPapua, []string{"ambon", "ternate", "jayapura", "sorong", "merauke", "manokwari"}},
+}
+
+var provinceRegions = []regionKeywords{
+	{RegionJawa, []string{"jawa", "banten", "dki"}},
+	{RegionBaliNusa, []string{"bali", "nusa tenggara", "ntb", "ntt"}},
+	{RegionSumatera, []string{"sumatera", "sumatra", "aceh", "riau", "lampung", "bangka", "belitung"}},
+	{RegionKalimantan, []string{"kalimantan"}},
+	{RegionSulawesi, []string{"sulawesi"}},
+	{RegionMalukuPapua, []string{"maluku", "papua"}},
+}
+
+// ratePerKg is the regular service price per started kilogram.
+var ratePerKg = map[string]float64{
+	RegionJawa:        10000,
+	RegionBaliNusa:    15000,
+	RegionSumatera:    15000,
+	RegionKalimantan:  20000,
+	RegionSulawesi:    22000,
+	RegionMalukuPapua: 35000,
+	RegionOther:       25000,
+}
+
+type localMethod struct {
+	model.ShippingMethod
+	multiplier float64
+	originOnly bool
+}
+
+var localMethods = []localMethod{
+	{model.ShippingMethod{Code: "regular", Name: "Regular", EstimatedDays: "3-5"}, 1, false},
+	{model.ShippingMethod{Code: "express", Name: "Express", EstimatedDays: "1-2"}, 1.8, false},
+	{model.ShippingMethod{Code: "same_day", Name: "Same Day", EstimatedDays: "0"}, 2.5, true},
+}
+
+// LocalCalculator prices parcels from rule tables: weight in started
+// kilograms times the destination region rate and a per-method multiplier.
+// Regular shipping is free once the subtotal reaches the configured
+// threshold.
+type LocalCalculator struct {
+	originRegion          string
+	freeShippingThreshold float64
+}
+
+func NewLocalCalculator(config util.Configuration) ShippingCalculator {
+	origin := config.Shipping.OriginRegion
+	if origin == "" {
+		origin = RegionJawa
+	}
+	return &LocalCalculator{originRegion: origin, freeShippingThreshold: config.Shipping.FreeShippingThreshold}
+}
+
+func (c *LocalCalculator) Methods() []model.ShippingMethod {
+	methods := make([]model.ShippingMethod, len(localMethods))
+	for i, m := range localMethods {
+		methods[i] = m.ShippingMethod
+	}
+	return methods
+}
+
+func (c *LocalCalculator) Quote(method string, req Request) (*model.ShippingQuote, error) {
+	var selected *localMethod
+	for i := range localMethods {
+		if localMethods[i].Code == method {
+			selected = &localMethods[i]
+			break
+		}
+	}
+	if selected == nil {
+		return nil, ErrUnknownMethod
+	}
+
+	region := ParseRegion(req.Address)
+	if selected.originOnly && region != c.originRegion {
+		return nil, ErrMethodNotAvailable
+	}
+
+	kilograms := math.Ceil(float64(req.WeightGrams) / 1000)
+	if kilograms < 1 {
+		kilograms = 1
+	}
+
+	quote := &model.ShippingQuote{
+		Method:        selected.Code,
+		Name:          selected.Name,
+		Region:        region,
+		WeightGrams:   req.WeightGrams,
+		Cost:          math.Round(kilograms * ratePerKg[region] * selected.multiplier),
+		EstimatedDays: selected.EstimatedDays,
+	}
+	if selected.Code == DefaultMethod && c.freeShippingThreshold > 0 && req.Subtotal >= c.freeShippingThreshold {
+		quote.Cost = 0
+		quote.FreeShipping = true
+	}
+
+	return quote, nil
+}
+
+func ParseRegion(address string) string {
+	// Padding the normalised words with spaces lets a plain substring search
+	// match whole words, including multi-word keywords like "nusa tenggara".
+	words := " " + strings.Join(strings.FieldsFunc(strings.ToLower(address), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}), " ") + " "
+
+	for _, table := range [][]regionKeywords{cityRegions, provinceRegions} {
+		for _, rk := range table {
+			for _, keyword := range rk.keywords {
+				if strings.Contains(words, " "+keyword+" ") {
+					return rk.region
+				}
+			}
+		}
+	}
+	return RegionOther
+}
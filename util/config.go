@@ -1,43 +1,165 @@
-package util
-
-import (
-	"log"
-	"os"
-	"github.com/joho/godotenv"
-)
-
-type Configuration struct {
-	AppName string
-	Port    string
-	Debug   bool
-	DB      DatabaseConfig
-}
-
-type DatabaseConfig struct {
-	Name     string
-	Username string
-	Password string
-	Host     string
-}
-
-func ReadConfiguration() Configuration {
-	if err := godotenv.Load(); err != nil {
-        log.Println("No .env file found. Using system environment variables.")
-    }
-	debug := false
-	if os.Getenv("DEBUG") == "true" {
-		debug = true
-	}
-
-	return Configuration{
-		AppName: os.Getenv("APP_NAME"),
-		Port:    os.Getenv("PORT"),
-		Debug:   debug,
-		DB: DatabaseConfig{
-			Name:     os.Getenv("DATABASE_NAME"),
-			Username: os.Getenv("DATABASE_USERNAME"),
-			Password: os.Getenv("DATABASE_PASSWORD"),
-			Host:     os.Getenv("DATABASE_HOST"),
-		},
-	}
-}
+package util
+
+import (
+	"log"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+	"github.com/joho/godotenv"
+)
+
+type Configuration struct {
+	AppName  string
+	Port     string
+	Debug    bool
+	DB       DatabaseConfig
+	Auth     AuthConfig
+	Notify   NotifyConfig
+	Payment  PaymentConfig
+	Shipping ShippingConfig
+}
+
+type DatabaseConfig struct {
+	Name     string
+	Username string
+	Password string
+	Host     string
+}
+
+const (
+	AuthModeSession = "session"
+	AuthModeJWT     = "jwt"
+)
+
+type AuthConfig struct {
+	Mode                   string
+	AccessTokenTTL         time.Duration
+	RefreshTokenTTL        time.Duration
+	SessionPurgeInterval   time.Duration
+	JWTAlgorithm           string
+	JWTKeys                []JWTKey
+	RevocationSyncInterval time.Duration
+	LoginMaxFailures       int
+	LoginMaxIPFailures     int
+	LoginBackoff           time.Duration
+	LoginLockout           time.Duration
+}
+
+// JWTKey is one entry of AUTH_JWT_KEYS. The first key signs new tokens; the
+// rest only verify, which lets keys be rotated without logging users out.
+type JWTKey struct {
+	ID     string
+	Secret string
+}
+
+type NotifyConfig struct {
+	Driver     string
+	OutboxFile string
+}
+
+type ShippingConfig struct {
+	OriginRegion          string
+	FreeShippingThreshold float64
+}
+
+type PaymentConfig struct {
+	Provider      string
+	WebhookSecret string
+}
+
+func ReadConfiguration() Configuration {
+	if err := godotenv.Load(); err != nil {
+        log.Println("No .env file found. Using system environment variables.")
+    }
+	debug := false
+	if os.Getenv("DEBUG") == "true" {
+		debug = true
+	}
+	freeShippingThreshold, _ := strconv.ParseFloat(os.Getenv("SHIPPING_FREE_THRESHOLD"), 64)
+
+	authMode := os.Getenv("AUTH_MODE")
+	if authMode == "" {
+		authMode = AuthModeSession
+	}
+	// Stateless tokens cannot be cut short without the revocation list, so
+	// they default to a much shorter life.
+	accessTokenTTL := time.Hour
+	if authMode == AuthModeJWT {
+		accessTokenTTL = 15 * time.Minute
+	}
+	jwtAlgorithm := os.Getenv("AUTH_JWT_ALG")
+	if jwtAlgorithm == "" {
+		jwtAlgorithm = "HS256"
+	}
+
+	return Configuration{
+		AppName: os.Getenv("APP_NAME"),
+		Port:    os.Getenv("PORT"),
+		Debug:   debug,
+		DB: DatabaseConfig{
+			Name:     os.Getenv("DATABASE_NAME"),
+			Username: os.Getenv("DATABASE_USERNAME"),
+			Password: os.Getenv("DATABASE_PASSWORD"),
+			Host:     os.Getenv("DATABASE_HOST"),
+		},
+		Auth: AuthConfig{
+			Mode:                   authMode,
+			AccessTokenTTL:         durationEnv("AUTH_ACCESS_TOKEN_TTL", accessTokenTTL),
+			RefreshTokenTTL:        durationEnv("AUTH_REFRESH_TOKEN_TTL", 30*24*time.Hour),
+			SessionPurgeInterval:   durationEnv("AUTH_SESSION_PURGE_INTERVAL", time.Hour),
+			JWTAlgorithm:           jwtAlgorithm,
+			JWTKeys:                parseJWTKeys(os.Getenv("AUTH_JWT_KEYS")),
+			RevocationSyncInterval: durationEnv("AUTH_JWT_REVOCATION_SYNC", 30*time.Second),
+			LoginMaxFailures:       intEnv("AUTH_LOGIN_MAX_FAILURES", 5),
+			LoginMaxIPFailures:     intEnv("AUTH_LOGIN_MAX_IP_FAILURES", 20),
+			LoginBackoff:           durationEnv("AUTH_LOGIN_BACKOFF", time.Second),
+			LoginLockout:           durationEnv("AUTH_LOGIN_LOCKOUT", 15*time.Minute),
+		},
+		Notify: NotifyConfig{
+			Driver:     os.Getenv("NOTIFY_DRIVER"),
+			OutboxFile: os.Getenv("NOTIFY_OUTBOX_FILE"),
+		},
+		Payment: PaymentConfig{
+			Provider:      os.Getenv("PAYMENT_PROVIDER"),
+			WebhookSecret: os.Getenv("PAYMENT_WEBHOOK_SECRET"),
+		},
+		Shipping: ShippingConfig{
+			OriginRegion:          os.Getenv("SHIPPING_ORIGIN_REGION"),
+			FreeShippingThreshold: freeShippingThreshold,
+		},
+	}
+}
+
+// durationEnv reads a Go duration such as "15m" or "720h", falling back when
+// the variable is unset or invalid.
+func durationEnv(key string, fallback time.Duration) time.Duration {
+	value, err := time.ParseDuration(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// intEnv reads a positive integer, falling back when the variable is unset
+// or invalid.
+func intEnv(key string, fallback int) int {
+	value, err := strconv.Atoi(os.Getenv(key))
+	if err != nil || value <= 0 {
+		return fallback
+	}
+	return value
+}
+
+// parseJWTKeys reads "kid:secret" pairs separated by commas.
+func parseJWTKeys(value string) []JWTKey {
+	var keys []JWTKey
+	for _, entry := range strings.Split(value, ",") {
+		id, secret, found := strings.Cut(strings.TrimSpace(entry), ":")
+		if !found || id == "" || secret == "" {
+			continue
+		}
+		keys = append(keys, JWTKey{ID: id, Secret: secret})
+	}
+	return keys
+}
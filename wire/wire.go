@@ -1,105 +1,150 @@
-//go:build wireinject
-// +build wireinject
-
-package wire
-
-import (
-	"ecommerce/database"
-	"ecommerce/handler"
-	"ecommerce/repository"
-	"ecommerce/router"
-	"ecommerce/service"
-	"ecommerce/util"
-	"log"
-	"os"
-	"strconv"
-
-	"github.com/go-chi/chi/v5"
-	"github.com/google/wire"
-	"github.com/joho/godotenv"
-	"go.uber.org/zap"
-	"go.uber.org/zap/zapcore"
-)
-
-func ProvideConfiguration() util.Configuration {
-	return util.ReadConfiguration()
-}
-
-var ConfigSet = wire.NewSet(ProvideConfiguration)
-
-func ProvideLogger() (*zap.Logger, error) {
-
-	err := godotenv.Load()
-	if err != nil {
-		log.Println("No .env file found. Using system environment variables.")
-		return nil, err
-	}
-
-	debugStr := os.Getenv("DEBUG")
-	debug, err := strconv.ParseBool(debugStr)
-	if err != nil {
-		debug = false
-	}
-
-	logLevel := zap.InfoLevel
-	if debug {
-		logLevel = zap.DebugLevel
-	}
-
-	encoderConfig := zapcore.EncoderConfig{
-		TimeKey:        "T",
-		LevelKey:       "L",
-		MessageKey:     "M",
-		CallerKey:      "C",
-		EncodeTime:     zapcore.ISO8601TimeEncoder,
-		EncodeLevel:    zapcore.CapitalLevelEncoder,
-		EncodeDuration: zapcore.StringDurationEncoder,
-		EncodeCaller:   zapcore.ShortCallerEncoder,
-	}
-
-	file, err := os.OpenFile("app.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
-	if err != nil {
-		log.Panicf("Failed to open log file: %v", err)
-		return nil, err
-	}
-
-	core := zapcore.NewTee(
-		zapcore.NewCore(
-			zapcore.NewJSONEncoder(encoderConfig),
-			zapcore.AddSync(file),
-			logLevel,
-		),
-		zapcore.NewCore(
-			zapcore.NewConsoleEncoder(encoderConfig),
-			zapcore.AddSync(os.Stdout),
-			logLevel,
-		),
-	)
-
-	logger := zap.New(core)
-	logger.Info("Logger initialized successfully")
-	return logger, nil
-}
-
-func InitializeRouterHandler() (*chi.Mux, error) {
-	wire.Build(
-		ConfigSet,
-		database.InitDB,
-		ProvideLogger,
-
-		repository.NewAuthRepository,
-		service.NewAuthService,
-		handler.NewAuthHandler,
-
-		repository.NewHomePageRepository,
-		service.NewHomePageService,
-		handler.NewHomePageHandler,
-
-		repository.NewCheckoutRepository,
-		service.NewCheckoutService,
-		handler.NewCheckoutHandler,
-
-		router.NewRouter,
-	)
-	return nil, nil
-}
+//go:build wireinject
+// +build wireinject
+
+package wire
+
+import (
+	"ecommerce/database"
+	"ecommerce/handler"
+	"ecommerce/jwt"
+	"ecommerce/notify"
+	"ecommerce/payment"
+	"ecommerce/repository"
+	"ecommerce/router"
+	"ecommerce/service"
+	"ecommerce/shipping"
+	"ecommerce/util"
+	"log"
+	"os"
+	"strconv"
+
+	"github.com/go-chi/chi/v5"
+	"github.com/google/wire"
+	"github.com/joho/godotenv"
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+)
+
+func ProvideConfiguration() util.Configuration {
+	return util.ReadConfiguration()
+}
+
+var ConfigSet = wire.NewSet(ProvideConfiguration)
+
+func ProvideLogger() (*zap.Logger, error) {
+
+	err := godotenv.Load()
+	if err != nil {
+		log.Println("No .env file found. Using system environment variables.")
+		return nil, err
+	}
+
+	debugStr := os.Getenv("DEBUG")
+	debug, err := strconv.ParseBool(debugStr)
+	if err != nil {
+		debug = false
+	}
+
+	logLevel := zap.InfoLevel
+	if debug {
+		logLevel = zap.DebugLevel
+	}
+
+	encoderConfig := zapcore.EncoderConfig{
+		TimeKey:        "T",
+		LevelKey:       "L",
+		MessageKey:     "M",
+		CallerKey:      "C",
+		EncodeTime:     zapcore.ISO8601TimeEncoder,
+		EncodeLevel:    zapcore.CapitalLevelEncoder,
+		EncodeDuration: zapcore.StringDurationEncoder,
+		EncodeCaller:   zapcore.ShortCallerEncoder,
+	}
+
+	file, err := os.OpenFile("app.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		log.Panicf("Failed to open log file: %v", err)
+		return nil, err
+	}
+
+	core := zapcore.NewTee(
+		zapcore.NewCore(
+			zapcore.NewJSONEncoder(encoderConfig),
+			zapcore.AddSync(file),
+			logLevel,
+		),
+		zapcore.NewCore(
+			zapcore.NewConsoleEncoder(encoderConfig),
+			zapcore.AddSync(os.Stdout),
+			logLevel,
+		),
+	)
+
+	logger := zap.New(core)
+	logger.Info("Logger initialized successfully")
+	return logger, nil
+}
+
+// Server is what main needs to run the application: the HTTP handler, and
+// the auth service whose background jobs share its in-memory state.
+type Server struct {
+	Router *chi.Mux
+	Auth   service.AuthService
+	Config util.Configuration
+}
+
+func NewServer(router *chi.Mux, auth service.AuthService, config util.Configuration) *Server {
+	return &Server{Router: router, Auth: auth, Config: config}
+}
+
+func InitializeServer() (*Server, error) {
+	wire.Build(
+		ConfigSet,
+		database.InitDB,
+		ProvideLogger,
+
+		jwt.NewManager,
+		notify.NewNotifier,
+		repository.NewAuthRepository,
+		service.NewAuthService,
+		handler.NewAuthHandler,
+
+		repository.NewHomePageRepository,
+		service.NewHomePageService,
+		handler.NewHomePageHandler,
+
+		shipping.NewLocalCalculator,
+		repository.NewCheckoutRepository,
+		service.NewCheckoutService,
+		handler.NewCheckoutHandler,
+
+		repository.NewCartRepository,
+		service.NewCartService,
+		handler.NewCartHandler,
+
+		repository.NewReviewRepository,
+		service.NewReviewService,
+		handler.NewReviewHandler,
+
+		repository.NewCatalogRepository,
+		service.NewCatalogService,
+		handler.NewCatalogHandler,
+
+		repository.NewCouponRepository,
+		service.NewCouponService,
+		handler.NewCouponHandler,
+
+		repository.NewPricingRepository,
+		service.NewPricingService,
+		handler.NewPricingHandler,
+
+		payment.NewPaymentProvider,
+		repository.NewPaymentRepository,
+		service.NewPaymentService,
+		handler.NewPaymentHandler,
+
+		router.NewRouter,
+		NewServer,
+	)
+	return nil, nil
+}
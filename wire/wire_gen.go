@@ -9,9 +9,13 @@ package wire
 import (
 	"ecommerce/database"
 	"ecommerce/handler"
+	"ecommerce/jwt"
+	"ecommerce/notify"
+	"ecommerce/payment"
 	"ecommerce/repository"
 	"ecommerce/router"
 	"ecommerce/service"
+	"ecommerce/shipping"
 	"ecommerce/util"
 	"github.com/go-chi/chi/v5"
 	"github.com/google/wire"
@@ -25,7 +29,7 @@ import (
 
 // Injectors from wire.go:
 
-func InitializeRouterHandler() (*chi.Mux, error) {
+func InitializeServer() (*Server, error) {
 	configuration := ProvideConfiguration()
 	logger, err := ProvideLogger()
 	if err != nil {
@@ -36,19 +40,51 @@ func InitializeRouterHandler() (*chi.Mux, error) {
 		return nil, err
 	}
 	checkoutRepository := repository.NewCheckoutRepository(db, logger)
-	checkoutService := service.NewCheckoutService(checkoutRepository)
+	shippingCalculator := shipping.NewLocalCalculator(configuration)
+	checkoutService := service.NewCheckoutService(checkoutRepository, shippingCalculator)
 	checkoutHandler := handler.NewCheckoutHandler(checkoutService, logger, configuration)
+	cartRepository := repository.NewCartRepository(db, logger)
+	cartService := service.NewCartService(cartRepository)
+	cartHandler := handler.NewCartHandler(cartService, logger, configuration)
 	homePageRepository := repository.NewHomePageRepository(db, logger)
 	homePageService := service.NewHomePageService(homePageRepository)
 	homePageHandler := handler.NewHomePageHandler(homePageService, logger, configuration)
 	authRepository := repository.NewAuthRepository(db, logger)
-	authService := service.NewAuthService(authRepository)
+	manager, err := jwt.NewManager(configuration)
+	if err != nil {
+		return nil, err
+	}
+	notifier, err := notify.NewNotifier(configuration, logger)
+	if err != nil {
+		return nil, err
+	}
+	authService := service.NewAuthService(authRepository, manager, notifier, configuration)
 	authHandler := handler.NewAuthHandler(authService, logger, configuration)
-	mux, err := router.NewRouter(checkoutHandler, homePageHandler, authHandler, authService, logger)
+	reviewRepository := repository.NewReviewRepository(db, logger)
+	reviewService := service.NewReviewService(reviewRepository)
+	reviewHandler := handler.NewReviewHandler(reviewService, logger, configuration)
+	catalogRepository := repository.NewCatalogRepository(db, logger)
+	catalogService := service.NewCatalogService(catalogRepository)
+	catalogHandler := handler.NewCatalogHandler(catalogService, logger, configuration)
+	paymentRepository := repository.NewPaymentRepository(db, logger, checkoutRepository)
+	paymentProvider, err := payment.NewPaymentProvider(configuration)
 	if err != nil {
 		return nil, err
 	}
-	return mux, nil
+	paymentService := service.NewPaymentService(paymentRepository, checkoutRepository, paymentProvider)
+	paymentHandler := handler.NewPaymentHandler(paymentService, logger, configuration)
+	couponRepository := repository.NewCouponRepository(db, logger)
+	couponService := service.NewCouponService(couponRepository)
+	couponHandler := handler.NewCouponHandler(couponService, logger, configuration)
+	pricingRepository := repository.NewPricingRepository(db, logger)
+	pricingService := service.NewPricingService(pricingRepository)
+	pricingHandler := handler.NewPricingHandler(pricingService, logger, configuration)
+	mux, err := router.NewRouter(checkoutHandler, cartHandler, homePageHandler, authHandler, reviewHandler, catalogHandler, paymentHandler, couponHandler, pricingHandler, authService, logger)
+	if err != nil {
+		return nil, err
+	}
+	server := NewServer(mux, authService, configuration)
+	return server, nil
 }
 
 // wire.go:
@@ -102,3 +138,15 @@ func ProvideLogger() (*zap.Logger, error) {
 	logger.Info("Logger initialized successfully")
 	return logger, nil
 }
+
+// Server is what main needs to run the application: the HTTP handler, and
+// the auth service whose background jobs share its in-memory state.
+type Server struct {
+	Router *chi.Mux
+	Auth   service.AuthService
+	Config util.Configuration
+}
+
+func NewServer(router *chi.Mux, auth service.AuthService, config util.Configuration) *Server {
+	return &Server{Router: router, Auth: auth, Config: config}
+}